@@ -0,0 +1,466 @@
+package raychip
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+type AnimationMode int
+
+const (
+	// AnimationLoop starts again from the first frame after the last
+	AnimationLoop AnimationMode = iota
+	// AnimationPingPong plays to the end and back again
+	AnimationPingPong
+	// AnimationOnce stops on the last frame and publishes AnimationFinished
+	AnimationOnce
+)
+
+type AnimationFrame struct {
+	Frame    SpriteFrame
+	Duration float64 // seconds
+	// Tag is published as an AnimationTag event when the frame comes up
+	Tag string
+}
+
+// Animation is a named sequence of frames from one sprite sheet
+type Animation struct {
+	Name    string
+	Frames  []AnimationFrame
+	Mode    AnimationMode
+	Reverse bool
+	// Repeat finishes a looping or ping-pong animation after that many
+	// passes, where one pass of a ping-pong goes one way. 0 repeats forever.
+	Repeat int
+}
+
+// NewAnimation plays the given frames of a sheet in order, each shown for
+// frameDuration seconds unless the sheet gives a duration of its own
+func NewAnimation(name string, sheet SpriteSheet, frames []int, frameDuration float64, mode AnimationMode) Animation {
+	animation := Animation{
+		Name: name,
+		Mode: mode,
+	}
+	for _, i := range frames {
+		frame := sheet.Frame(i)
+		duration := frameDuration
+		if frame.Duration > 0 {
+			duration = frame.Duration
+		}
+		animation.Frames = append(animation.Frames, AnimationFrame{Frame: frame, Duration: duration})
+	}
+	return animation
+}
+
+// FrameRange is the indices from first to last inclusive, for NewAnimation
+func FrameRange(first int, last int) []int {
+	var frames []int
+	for i := first; i <= last; i++ {
+		frames = append(frames, i)
+	}
+	return frames
+}
+
+// TagFrame marks frame i, counted within the animation, so an AnimationTag
+// event is published whenever it comes up
+func (a *Animation) TagFrame(i int, tag string) {
+	a.Frames[i].Tag = tag
+}
+
+// Duration is how long one pass through the frames takes
+func (a Animation) Duration() float64 {
+	total := 0.0
+	for _, frame := range a.Frames {
+		total += frame.Duration
+	}
+	return total
+}
+
+// Animation events are published on the game's EventBus under
+// "animation.finished" and "animation.tag". Entity is the entity the
+// animator is set on.
+type AnimationFinished struct {
+	Entity    Entity
+	Animation string
+}
+
+type AnimationTag struct {
+	Entity    Entity
+	Animation string
+	Frame     int
+	Tag       string
+}
+
+// Animator plays animations by switching the frame its sprite shows. It is
+// advanced by the game every frame once it is set on an entity, either with
+// SetAnimator or by adding its Sprite to the game. An animator should only be
+// used by one entity.
+type Animator struct {
+	sprite     Sprite
+	animations map[string]*Animation
+	current    *Animation
+	position   int
+	step       int
+	passes     int
+	elapsed    float64
+	speed      float64
+	playing    bool
+	finished   bool
+	// the current frame's tag still needs publishing
+	entered bool
+}
+
+func NewAnimator(sheet SpriteSheet) *Animator {
+	a := &Animator{
+		sprite:     NewSprite(0, 0, sheet.Texture()),
+		animations: make(map[string]*Animation),
+		speed:      1,
+	}
+	a.sprite.sheet = &sheet
+	if sheet.Len() > 0 {
+		a.sprite.frame = sheet.Frame(0)
+	}
+	a.sprite.animator = a
+	return a
+}
+
+// Sprite is what the animator draws with. Its flip, scale, origin and tint
+// apply to every frame, and it can be added to a game as an entity itself.
+func (a *Animator) Sprite() *Sprite {
+	return &a.sprite
+}
+
+func (a *Animator) AddAnimation(animation Animation) {
+	a.animations[animation.Name] = &animation
+}
+
+func (a *Animator) Animation(name string) (Animation, bool) {
+	animation, ok := a.animations[name]
+	if !ok {
+		return Animation{}, false
+	}
+	return *animation, true
+}
+
+// TagFrame tags frame i of a named animation, see Animation.TagFrame
+func (a *Animator) TagFrame(name string, i int, tag string) {
+	if animation, ok := a.animations[name]; ok {
+		animation.TagFrame(i, tag)
+	}
+}
+
+// Play switches to a named animation from its first frame. Playing the
+// animation that is already playing carries on with it, use Restart to go
+// back to the start.
+func (a *Animator) Play(name string) error {
+	animation, ok := a.animations[name]
+	if !ok {
+		return fmt.Errorf("no animation named %q", name)
+	}
+	if a.current == animation && a.playing {
+		return nil
+	}
+	a.current = animation
+	a.Restart()
+	return nil
+}
+
+func (a *Animator) Restart() {
+	if a.current == nil || len(a.current.Frames) == 0 {
+		return
+	}
+	a.position = 0
+	a.step = 1
+	a.passes = 0
+	a.elapsed = 0
+	a.playing = true
+	a.finished = false
+	a.entered = true
+	a.showFrame()
+}
+
+func (a *Animator) Pause() {
+	a.playing = false
+}
+
+// Resume carries on after Pause, it does nothing once an animation has
+// finished
+func (a *Animator) Resume() {
+	if a.current != nil && !a.finished {
+		a.playing = true
+	}
+}
+
+func (a Animator) IsPlaying() bool {
+	return a.playing
+}
+
+func (a Animator) IsFinished() bool {
+	return a.finished
+}
+
+// Current is the name of the animation playing, or last played
+func (a Animator) Current() string {
+	if a.current == nil {
+		return ""
+	}
+	return a.current.Name
+}
+
+// FrameIndex is the frame being shown, counted within the animation
+func (a Animator) FrameIndex() int {
+	if a.current == nil {
+		return 0
+	}
+	return a.frameIndex()
+}
+
+// SetSpeed scales how fast animations play, 1 is normal speed
+func (a *Animator) SetSpeed(speed float64) {
+	if speed >= 0 {
+		a.speed = speed
+	}
+}
+
+func (a Animator) Speed() float64 {
+	return a.speed
+}
+
+// Draw draws the current frame on an entity, for use in draw callbacks
+func (a *Animator) Draw(entity Entity) {
+	e := entity.base()
+	a.sprite.drawAt(e.renderer(), e.RenderPosition(), e.RenderAngle()+a.sprite.Angle())
+}
+
+func (a *Animator) frameIndex() int {
+	if a.current.Reverse {
+		return len(a.current.Frames) - 1 - a.position
+	}
+	return a.position
+}
+
+func (a *Animator) showFrame() {
+	a.sprite.frame = a.current.Frames[a.frameIndex()].Frame
+}
+
+// update advances the animation by dt seconds and publishes its events
+func (a *Animator) update(game *Game, entity Entity, dt float64) {
+	if a.current == nil {
+		return
+	}
+	bus := &game.EventBus
+	publishTag := func() {
+		a.entered = false
+		i := a.frameIndex()
+		if tag := a.current.Frames[i].Tag; tag != "" {
+			publishIfSubscribed(bus, "animation.tag", AnimationTag{Entity: entity, Animation: a.current.Name, Frame: i, Tag: tag})
+		}
+	}
+	if a.entered {
+		publishTag()
+	}
+	// without any duration the animation would never get past a frame
+	if !a.playing || a.current.Duration() <= 0 {
+		return
+	}
+
+	a.elapsed += dt * a.speed
+	for a.playing && a.elapsed >= a.current.Frames[a.frameIndex()].Duration {
+		a.elapsed -= a.current.Frames[a.frameIndex()].Duration
+		before := a.position
+		more := a.next()
+		// a ping-pong can finish on a new frame
+		if a.position != before {
+			a.showFrame()
+			publishTag()
+		}
+		if !more {
+			a.playing = false
+			a.finished = true
+			a.elapsed = 0
+			publishIfSubscribed(bus, "animation.finished", AnimationFinished{Entity: entity, Animation: a.current.Name})
+		}
+	}
+}
+
+// next moves on a frame, or reports false when the animation is over
+func (a *Animator) next() bool {
+	last := len(a.current.Frames) - 1
+	switch a.current.Mode {
+	case AnimationOnce:
+		if a.position == last {
+			return false
+		}
+		a.position++
+	case AnimationPingPong:
+		if last == 0 {
+			return a.endPass()
+		}
+		a.position += a.step
+		if a.position == last || a.position == 0 {
+			a.step = -a.step
+			return a.endPass()
+		}
+	default:
+		if a.position == last {
+			a.position = 0
+			return a.endPass()
+		}
+		a.position++
+	}
+	return true
+}
+
+// endPass counts a pass through the animation, reporting false if that was
+// the last one
+func (a *Animator) endPass() bool {
+	a.passes++
+	if a.current.Repeat > 0 && a.passes >= a.current.Repeat {
+		// stay on the frame the last pass ended on
+		if a.current.Mode == AnimationLoop {
+			a.position = len(a.current.Frames) - 1
+		}
+		return false
+	}
+	return true
+}
+
+// SetAnimator has the game advance an animator every frame while the entity
+// is in it. Boxes, circles and polygons also draw themselves with it.
+func (e *EntityBase) SetAnimator(animator *Animator) {
+	e.animator = animator
+}
+
+func (e EntityBase) Animator() *Animator {
+	return e.animator
+}
+
+// SetAnimator draws the box with an animator's sprite and has the game
+// advance it every frame
+func (b *Box) SetAnimator(animator *Animator) {
+	b.EntityBase.SetAnimator(animator)
+	b.SetSprite(animator.Sprite())
+}
+
+// SetAnimator draws the circle with an animator's sprite and has the game
+// advance it every frame
+func (c *Circle) SetAnimator(animator *Animator) {
+	c.EntityBase.SetAnimator(animator)
+	c.SetSprite(animator.Sprite())
+}
+
+// SetAnimator draws the polygon with an animator's sprite and has the game
+// advance it every frame
+func (p *Polygon) SetAnimator(animator *Animator) {
+	p.EntityBase.SetAnimator(animator)
+	p.SetSprite(animator.Sprite())
+}
+
+// OnAnimationFinished calls back when an animation played by this entity's
+// animator finishes
+func (e *EntityBase) OnAnimationFinished(game *Game, callback func(animation string)) SubscriptionID {
+	id := Subscribe(&game.EventBus, "animation.finished", func(event AnimationFinished) {
+		if !e.inGame(game) {
+			return
+		}
+		if event.Entity.base() == e {
+			callback(event.Animation)
+		}
+	})
+
+	return e.unsubscribeOnRemove(game, id)
+}
+
+// OnAnimationTag calls back when this entity's animator shows a tagged frame
+func (e *EntityBase) OnAnimationTag(game *Game, callback func(animation string, tag string)) SubscriptionID {
+	id := Subscribe(&game.EventBus, "animation.tag", func(event AnimationTag) {
+		if !e.inGame(game) {
+			return
+		}
+		if event.Entity.base() == e {
+			callback(event.Animation, event.Tag)
+		}
+	})
+
+	return e.unsubscribeOnRemove(game, id)
+}
+
+type asepriteTag struct {
+	Name      string `json:"name"`
+	From      int    `json:"from"`
+	To        int    `json:"to"`
+	Direction string `json:"direction"`
+	// newer versions write the repeat count as a string
+	Repeat json.RawMessage `json:"repeat"`
+}
+
+func (t asepriteTag) repeat() (int, error) {
+	raw := bytes.Trim(bytes.TrimSpace(t.Repeat), `"`)
+	if len(raw) == 0 {
+		return 0, nil
+	}
+	return strconv.Atoi(string(raw))
+}
+
+// NewAsepriteAnimator makes an animator from a JSON sheet exported by
+// Aseprite, hash or array. Each tag becomes an animation, with its direction
+// and repeat count, and the frames keep the durations set in Aseprite. A
+// sheet without tags gives a single looping animation named "default".
+func NewAsepriteAnimator(texture rl.Texture2D, data []byte) (*Animator, error) {
+	sheet, err := NewAtlasSpriteSheet(texture, data)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Meta struct {
+			FrameTags []asepriteTag `json:"frameTags"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	animator := NewAnimator(sheet)
+	tags := file.Meta.FrameTags
+	if len(tags) == 0 {
+		tags = []asepriteTag{{Name: "default", From: 0, To: sheet.Len() - 1}}
+	}
+	for _, tag := range tags {
+		if tag.From < 0 || tag.To >= sheet.Len() || tag.From > tag.To {
+			return nil, fmt.Errorf("aseprite tag %q has frames %d to %d out of %d", tag.Name, tag.From, tag.To, sheet.Len())
+		}
+		repeat, err := tag.repeat()
+		if err != nil {
+			return nil, fmt.Errorf("aseprite tag %q has invalid repeat: %w", tag.Name, err)
+		}
+		animation := NewAnimation(tag.Name, sheet, FrameRange(tag.From, tag.To), 0.1, AnimationLoop)
+		animation.Repeat = repeat
+		switch tag.Direction {
+		case "", "forward":
+		case "reverse":
+			animation.Reverse = true
+		case "pingpong":
+			animation.Mode = AnimationPingPong
+		case "pingpong_reverse":
+			animation.Mode = AnimationPingPong
+			animation.Reverse = true
+		default:
+			return nil, fmt.Errorf("aseprite tag %q has unknown direction %q", tag.Name, tag.Direction)
+		}
+		animator.AddAnimation(animation)
+	}
+	return animator, nil
+}
+
+func LoadAsepriteAnimator(texture rl.Texture2D, path string) (*Animator, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewAsepriteAnimator(texture, data)
+}
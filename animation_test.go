@@ -0,0 +1,220 @@
+package raychip
+
+import (
+	"reflect"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// newStripSheet is n 10x10 frames side by side
+func newStripSheet(t *testing.T, n int) SpriteSheet {
+	t.Helper()
+	sheet, err := NewGridSpriteSheet(rl.Texture2D{Width: int32(10 * n), Height: 10}, SpriteGrid{TileWidth: 10, TileHeight: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sheet
+}
+
+func TestAnimatorModes(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     AnimationMode
+		reverse  bool
+		repeat   int
+		first    int
+		frames   []int
+		finished bool
+	}{
+		{"loop", AnimationLoop, false, 0, 0, []int{1, 2, 0, 1, 2, 0}, false},
+		{"loop reversed", AnimationLoop, true, 0, 2, []int{1, 0, 2, 1, 0, 2}, false},
+		{"loop repeated", AnimationLoop, false, 2, 0, []int{1, 2, 0, 1, 2, 2}, true},
+		{"once", AnimationOnce, false, 0, 0, []int{1, 2, 2, 2}, true},
+		{"ping-pong", AnimationPingPong, false, 0, 0, []int{1, 2, 1, 0, 1, 2}, false},
+		{"ping-pong reversed", AnimationPingPong, true, 0, 2, []int{1, 0, 1, 2, 1, 0}, false},
+		// one pass of a ping-pong goes one way
+		{"ping-pong repeated", AnimationPingPong, false, 3, 0, []int{1, 2, 1, 0, 1, 2, 2, 2}, true},
+	}
+	for _, test := range tests {
+		game := NewHeadlessGame(200, 100, 10)
+		animation := NewAnimation("walk", newStripSheet(t, 3), FrameRange(0, 2), 0.1, test.mode)
+		animation.Reverse = test.reverse
+		animation.Repeat = test.repeat
+		animator := NewAnimator(newStripSheet(t, 3))
+		animator.AddAnimation(animation)
+		if err := animator.Play("walk"); err != nil {
+			t.Fatal(err)
+		}
+		if got := animator.FrameIndex(); got != test.first {
+			t.Errorf("%s: started on frame %d, want %d", test.name, got, test.first)
+		}
+
+		var frames []int
+		for range test.frames {
+			animator.update(&game, nil, 0.1)
+			frames = append(frames, animator.FrameIndex())
+		}
+		if !reflect.DeepEqual(frames, test.frames) {
+			t.Errorf("%s: played frames %v, want %v", test.name, frames, test.frames)
+		}
+		if animator.IsFinished() != test.finished {
+			t.Errorf("%s: finished is %v, want %v", test.name, animator.IsFinished(), test.finished)
+		}
+		// the sprite shows whatever frame the animator is on
+		want := animation.Frames[animator.FrameIndex()].Frame
+		if got := animator.Sprite().Frame(); got != want {
+			t.Errorf("%s: sprite shows %v, want %v", test.name, got.Source, want.Source)
+		}
+	}
+}
+
+func TestAnimatorEvents(t *testing.T) {
+	game := NewHeadlessGame(200, 100, 10)
+	animator := NewAnimator(newStripSheet(t, 3))
+	animation := NewAnimation("attack", newStripSheet(t, 3), FrameRange(0, 2), 0.1, AnimationOnce)
+	animation.TagFrame(0, "windup")
+	animation.TagFrame(2, "hit")
+	animator.AddAnimation(animation)
+	box := NewBox(50, 50, 10, 10, rl.Red)
+	game.AddEntity(&box)
+	box.SetAnimator(animator)
+
+	var tags []string
+	var finished []string
+	box.OnAnimationTag(&game, func(animation string, tag string) {
+		tags = append(tags, animation+" "+tag)
+	})
+	box.OnAnimationFinished(&game, func(animation string) {
+		finished = append(finished, animation)
+	})
+	animator.Play("attack")
+
+	game.RunFrames(5)
+	if want := []string{"attack windup", "attack hit"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("tags %v, want %v", tags, want)
+	}
+	if want := []string{"attack"}; !reflect.DeepEqual(finished, want) {
+		t.Errorf("finished %v, want %v", finished, want)
+	}
+
+	// a cleared entity's animator stops, and its callbacks with it
+	tags, finished = nil, nil
+	animator.Restart()
+	game.ClearEntities()
+	game.RunFrames(5)
+	if tags != nil || finished != nil {
+		t.Errorf("cleared entity still heard tags %v and finished %v", tags, finished)
+	}
+}
+
+func TestAnimatorSpeed(t *testing.T) {
+	game := NewHeadlessGame(200, 100, 10)
+	animator := NewAnimator(newStripSheet(t, 3))
+	animator.AddAnimation(NewAnimation("walk", newStripSheet(t, 3), FrameRange(0, 2), 0.1, AnimationLoop))
+	animator.Play("walk")
+
+	animator.SetSpeed(0.5)
+	animator.update(&game, nil, 0.1)
+	if got := animator.FrameIndex(); got != 0 {
+		t.Errorf("half speed moved on to frame %d after half a frame", got)
+	}
+	animator.update(&game, nil, 0.1)
+	if got := animator.FrameIndex(); got != 1 {
+		t.Errorf("half speed on frame %d after a whole frame, want 1", got)
+	}
+
+	animator.Pause()
+	animator.update(&game, nil, 1)
+	if got := animator.FrameIndex(); got != 1 {
+		t.Errorf("paused animation moved on to frame %d", got)
+	}
+
+	// playing the same animation carries on rather than restarting
+	animator.Resume()
+	animator.Play("walk")
+	if got := animator.FrameIndex(); got != 1 {
+		t.Errorf("playing again went back to frame %d", got)
+	}
+	if err := animator.Play("run"); err == nil {
+		t.Errorf("played an animation that doesn't exist")
+	}
+}
+
+func TestAsepriteAnimator(t *testing.T) {
+	data := `{
+		"frames": [
+			{"filename": "knight 0.aseprite", "frame": {"x": 0, "y": 0, "w": 16, "h": 16}, "duration": 100},
+			{"filename": "knight 1.aseprite", "frame": {"x": 16, "y": 0, "w": 16, "h": 16}, "duration": 250},
+			{"filename": "knight 2.aseprite", "frame": {"x": 32, "y": 0, "w": 16, "h": 16}, "duration": 100},
+			{"filename": "knight 3.aseprite", "frame": {"x": 48, "y": 0, "w": 16, "h": 16}, "duration": 100}
+		],
+		"meta": {
+			"frameTags": [
+				{"name": "idle", "from": 0, "to": 1, "direction": "forward"},
+				{"name": "walk", "from": 1, "to": 3, "direction": "pingpong", "repeat": "2"},
+				{"name": "back", "from": 2, "to": 3, "direction": "reverse", "repeat": 1},
+				{"name": "bounce", "from": 0, "to": 3, "direction": "pingpong_reverse"}
+			]
+		}
+	}`
+	animator, err := NewAsepriteAnimator(rl.Texture2D{Width: 64, Height: 16}, []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		mode    AnimationMode
+		reverse bool
+		repeat  int
+		first   float32
+		length  int
+	}{
+		{"idle", AnimationLoop, false, 0, 0, 2},
+		{"walk", AnimationPingPong, false, 2, 16, 3},
+		{"back", AnimationLoop, true, 1, 32, 2},
+		{"bounce", AnimationPingPong, true, 0, 0, 4},
+	}
+	for _, test := range tests {
+		animation, ok := animator.Animation(test.name)
+		if !ok {
+			t.Errorf("no %s animation", test.name)
+			continue
+		}
+		if animation.Mode != test.mode || animation.Reverse != test.reverse || animation.Repeat != test.repeat {
+			t.Errorf("%s: mode %v reverse %v repeat %d, want %v %v %d", test.name,
+				animation.Mode, animation.Reverse, animation.Repeat, test.mode, test.reverse, test.repeat)
+		}
+		if len(animation.Frames) != test.length || animation.Frames[0].Frame.Source.X != test.first {
+			t.Errorf("%s: %d frames from x %v, want %d from x %v", test.name,
+				len(animation.Frames), animation.Frames[0].Frame.Source.X, test.length, test.first)
+		}
+	}
+
+	// durations come from the file, in milliseconds
+	idle, _ := animator.Animation("idle")
+	if idle.Frames[0].Duration != 0.1 || idle.Frames[1].Duration != 0.25 {
+		t.Errorf("idle frame durations %v and %v, want 0.1 and 0.25", idle.Frames[0].Duration, idle.Frames[1].Duration)
+	}
+
+	untagged := `{"frames": {"a": {"frame": {"x": 0, "y": 0, "w": 8, "h": 8}}, "b": {"frame": {"x": 8, "y": 0, "w": 8, "h": 8}}}}`
+	animator, err = NewAsepriteAnimator(rl.Texture2D{}, []byte(untagged))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if animation, ok := animator.Animation("default"); !ok || len(animation.Frames) != 2 || animation.Mode != AnimationLoop {
+		t.Errorf("untagged sheet gave default animation %+v", animation)
+	}
+
+	bad := []string{
+		`{"frames": [{"frame": {"w": 8, "h": 8}}], "meta": {"frameTags": [{"name": "a", "from": 0, "to": 1}]}}`,
+		`{"frames": [{"frame": {"w": 8, "h": 8}}], "meta": {"frameTags": [{"name": "a", "from": 0, "to": 0, "direction": "sideways"}]}}`,
+		`{"frames": [{"frame": {"w": 8, "h": 8}}], "meta": {"frameTags": [{"name": "a", "from": 0, "to": 0, "repeat": "lots"}]}}`,
+	}
+	for _, data := range bad {
+		if _, err := NewAsepriteAnimator(rl.Texture2D{}, []byte(data)); err == nil {
+			t.Errorf("accepted %s", data)
+		}
+	}
+}
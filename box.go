@@ -76,24 +76,27 @@ func (b Box) limitVelocity(body *cp.Body, gravity cp.Vector, damping float64, dt
 }
 
 func (e *Box) addToGame(game *Game, args ...any) {
-	if e.physical {
+	if e.physical || e.sensor {
 		if body, ok := args[0].(*cp.Body); ok {
 			if shape, ok := args[1].(*cp.Shape); ok {
 				game.physical = true
-				body = game.space.AddBody(cp.NewBody(e.mass, cp.MomentForBox(e.mass, float64(e.rectangle.Width), float64(e.rectangle.Height))))
+				if e.physical {
+					body = game.space.AddBody(cp.NewBody(e.mass, cp.MomentForBox(e.mass, float64(e.rectangle.Width), float64(e.rectangle.Height))))
+					body.SetVelocityUpdateFunc(e.limitVelocity)
+				} else {
+					body = game.space.AddBody(newSensorBody())
+				}
 				body.SetPosition(cp.Vector{X: e.position.X, Y: e.position.Y})
 				body.SetVelocity(e.velocity.X, e.velocity.Y)
 				shape = game.space.AddShape(cp.NewBox(body, float64(e.rectangle.Width), float64(e.rectangle.Height), 0))
 				shape.SetElasticity(e.elasticity)
 				shape.SetFriction(e.friction)
-				body.SetVelocityUpdateFunc(e.limitVelocity)
 				e.cpBody = body
 				e.cpShape = shape
 			}
 		}
 	}
-	e.id = uint64(len(game.entities))
-	game.entities = append(game.entities, e)
+	game.registerEntity(e)
 }
 
 func (b *Box) Update() {
@@ -109,10 +112,9 @@ func (b *Box) Draw() {
 }
 
 func defaultBoxDrawFunc(b *Box) {
-	angle := b.Angle() * 180.0 / math.Pi
-	pos := b.Position()
+	pos := b.RenderPosition()
 	boxRect := rl.NewRectangle(float32(pos.X), float32(pos.Y), float32(b.rectangle.Width), float32(b.rectangle.Height))
-    rl.DrawRectanglePro(boxRect, rl.NewVector2(boxRect.Width/2, boxRect.Height/2), float32(angle), b.color)
+	b.renderer().DrawRectangle(boxRect, NewVector2(b.Width()/2, b.Height()/2), b.RenderAngle(), b.color)
 }
 
 func (b Box) DefaultDraw() {
@@ -123,55 +125,31 @@ func (b *Box) SetDrawCallback(callback func(*Box)) {
 	b.drawCallback = callback
 }
 
+// SetSprite draws the box as a sprite, which doesn't need to be added to the
+// game itself
+func (b *Box) SetSprite(sprite *Sprite) {
+	b.SetDrawCallback(func(b *Box) {
+		sprite.drawAt(b.renderer(), b.RenderPosition(), b.RenderAngle()+sprite.Angle())
+	})
+}
+
 func (b *Box) SetUpdateCallback(callback func(*Box)) {
 	b.updateCallback = callback
 }
 
-func (b *Box) OnClick(game *Game, button rl.MouseButton, state MouseState, callback func()) int {
-	id := game.EventBus.CreateSubscription("input.mouse", MouseInputEvent{}, func(input MouseInputEvent) {
-		var clicked = false
-		switch state {
-		case MousePressed:
-			if input.IsButtonPressed(button) {
-				clicked = true
-			}
-		case MouseReleased:
-			if input.IsButtonReleased(button) {
-				clicked = true
-			}
-		case MouseUp:
-			if input.IsButtonUp(button) {
-				clicked = true
-			}
-		case MouseDown:
-			if input.IsButtonDown(button) {
-				clicked = true
-			}
-		}
-
-		if clicked {
-			boxRect := rl.NewRectangle(
-                float32(b.position.X-b.Width()/2.0),
-				float32(b.position.Y-b.Height()/2.0),
-				float32(b.Width()),
-				float32(b.Height()),
-			)
-			if rl.CheckCollisionPointRec(game.mousePosition.ToRaylib(), boxRect) {
-				callback()
-			}
-		}
-	})
-
-	return id
+func (b *Box) OnClick(game *Game, button rl.MouseButton, state MouseState, callback func()) SubscriptionID {
+	return b.onClick(game, button, state, b.CheckMouseCollision, callback)
 }
 
-func (b Box) CheckMouseCollision(mousePos Vector2) bool {
-    adjRect := b.rectangle
-    adjRect.X -= b.rectangle.Width/2
-    adjRect.Y -= b.rectangle.Height/2
-    if rl.CheckCollisionPointRec(mousePos.ToRaylib(), adjRect) {
-        return true
-    } else {
-        return false
-    }
+// CheckMouseCollision says whether the mouse, in the coordinates of the box's
+// layer, is over the box where it is now, ignoring its rotation
+func (b *Box) CheckMouseCollision(mousePos Vector2) bool {
+	pos := b.Position()
+	boxRect := rl.NewRectangle(
+		float32(pos.X-b.Width()/2.0),
+		float32(pos.Y-b.Height()/2.0),
+		float32(b.Width()),
+		float32(b.Height()),
+	)
+	return rl.CheckCollisionPointRec(mousePos.ToRaylib(), boxRect)
 }
@@ -0,0 +1,217 @@
+package raychip
+
+import (
+	"math"
+	"math/rand/v2"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Camera decides which part of the world is drawn. By default it shows the
+// world one to one with the screen, so world and screen coordinates match.
+type Camera struct {
+	target       Vector2
+	offset       Vector2
+	zoom         float64
+	rotation     float64
+	screenWidth  float64
+	screenHeight float64
+
+	following Entity
+	deadzone  Vector2
+	smoothing float64
+
+	hasBounds bool
+	bounds    rl.Rectangle
+
+	shakeIntensity float64
+	shakeDuration  float64
+	shakeTime      float64
+	shakeOffset    Vector2
+	rng            *rand.Rand
+}
+
+func NewCamera(screenWidth int32, screenHeight int32) Camera {
+	center := NewVector2(float64(screenWidth)/2, float64(screenHeight)/2)
+	return Camera{
+		target:       center,
+		offset:       center,
+		zoom:         1.0,
+		screenWidth:  float64(screenWidth),
+		screenHeight: float64(screenHeight),
+		rng:          rand.New(rand.NewPCG(1, 2)),
+	}
+}
+
+// SetTarget sets the world point the camera looks at
+func (c *Camera) SetTarget(target Vector2) {
+	c.target = target
+	c.clampToBounds()
+}
+
+func (c Camera) Target() Vector2 {
+	return c.target
+}
+
+// SetOffset sets where on screen the target appears, the centre by default
+func (c *Camera) SetOffset(offset Vector2) {
+	c.offset = offset
+}
+
+func (c Camera) Offset() Vector2 {
+	return c.offset
+}
+
+func (c *Camera) SetZoom(zoom float64) {
+	if zoom > 0 {
+		c.zoom = zoom
+		c.clampToBounds()
+	}
+}
+
+func (c Camera) Zoom() float64 {
+	return c.zoom
+}
+
+// SetRotation rotates the view, in radians like entity angles
+func (c *Camera) SetRotation(rotation float64) {
+	c.rotation = rotation
+}
+
+func (c Camera) Rotation() float64 {
+	return c.rotation
+}
+
+// Follow keeps the entity in view, see SetDeadzone and SetSmoothing
+func (c *Camera) Follow(entity Entity) {
+	c.following = entity
+}
+
+func (c *Camera) StopFollowing() {
+	c.following = nil
+}
+
+// SetDeadzone lets the followed entity move within a box of this size around
+// the target, in world units, before the camera starts to move
+func (c *Camera) SetDeadzone(width float64, height float64) {
+	c.deadzone = NewVector2(width/2, height/2)
+}
+
+// SetSmoothing sets how quickly the camera catches up with the followed
+// entity, larger is faster. Zero snaps to it straight away.
+func (c *Camera) SetSmoothing(smoothing float64) {
+	c.smoothing = smoothing
+}
+
+// SetBounds keeps the view inside a rectangle of the world
+func (c *Camera) SetBounds(bounds rl.Rectangle) {
+	c.hasBounds = true
+	c.bounds = bounds
+	c.clampToBounds()
+}
+
+func (c *Camera) ClearBounds() {
+	c.hasBounds = false
+}
+
+// Shake jitters the view by up to intensity pixels, easing off over duration seconds
+func (c *Camera) Shake(intensity float64, duration float64) {
+	c.shakeIntensity = intensity
+	c.shakeDuration = duration
+	c.shakeTime = duration
+}
+
+func (c *Camera) update(dt float64) {
+	if c.following != nil {
+		pos := c.following.base().RenderPosition()
+		desired := c.target
+		if pos.X > c.target.X+c.deadzone.X {
+			desired.X = pos.X - c.deadzone.X
+		} else if pos.X < c.target.X-c.deadzone.X {
+			desired.X = pos.X + c.deadzone.X
+		}
+		if pos.Y > c.target.Y+c.deadzone.Y {
+			desired.Y = pos.Y - c.deadzone.Y
+		} else if pos.Y < c.target.Y-c.deadzone.Y {
+			desired.Y = pos.Y + c.deadzone.Y
+		}
+
+		if c.smoothing > 0 {
+			t := 1 - math.Exp(-c.smoothing*dt)
+			c.target.X += (desired.X - c.target.X) * t
+			c.target.Y += (desired.Y - c.target.Y) * t
+		} else {
+			c.target = desired
+		}
+	}
+	c.clampToBounds()
+
+	if c.shakeTime > 0 {
+		c.shakeTime = math.Max(c.shakeTime-dt, 0)
+		strength := c.shakeIntensity * c.shakeTime / c.shakeDuration
+		c.shakeOffset = NewVector2((c.rng.Float64()*2-1)*strength, (c.rng.Float64()*2-1)*strength)
+	} else {
+		c.shakeOffset = Vector2{}
+	}
+}
+
+func (c *Camera) clampToBounds() {
+	if !c.hasBounds {
+		return
+	}
+	halfWidth := c.screenWidth / 2 / c.zoom
+	halfHeight := c.screenHeight / 2 / c.zoom
+	c.target.X = clampView(c.target.X, float64(c.bounds.X), float64(c.bounds.X+c.bounds.Width), halfWidth)
+	c.target.Y = clampView(c.target.Y, float64(c.bounds.Y), float64(c.bounds.Y+c.bounds.Height), halfHeight)
+}
+
+// clampView keeps a view of half size half centred at v inside [min, max],
+// or centres it if the view is bigger than the bounds
+func clampView(v float64, min float64, max float64, half float64) float64 {
+	if max-min < 2*half {
+		return (min + max) / 2
+	}
+	return math.Max(min+half, math.Min(v, max-half))
+}
+
+func (c Camera) ToRaylib() rl.Camera2D {
+	return rl.Camera2D{
+		Offset:   NewVector2(c.offset.X+c.shakeOffset.X, c.offset.Y+c.shakeOffset.Y).ToRaylib(),
+		Target:   c.target.ToRaylib(),
+		Rotation: float32(c.rotation * 180.0 / math.Pi),
+		Zoom:     float32(c.zoom),
+	}
+}
+
+// WorldToScreen does the same transform as raylib's BeginMode2D
+func (c Camera) WorldToScreen(v Vector2) Vector2 {
+	sin, cos := math.Sincos(c.rotation)
+	x := (v.X - c.target.X) * c.zoom
+	y := (v.Y - c.target.Y) * c.zoom
+	return NewVector2(
+		x*cos-y*sin+c.offset.X+c.shakeOffset.X,
+		x*sin+y*cos+c.offset.Y+c.shakeOffset.Y,
+	)
+}
+
+func (c Camera) ScreenToWorld(v Vector2) Vector2 {
+	sin, cos := math.Sincos(-c.rotation)
+	x := (v.X - c.offset.X - c.shakeOffset.X) / c.zoom
+	y := (v.Y - c.offset.Y - c.shakeOffset.Y) / c.zoom
+	return NewVector2(
+		x*cos-y*sin+c.target.X,
+		x*sin+y*cos+c.target.Y,
+	)
+}
+
+func (game *Game) Camera() *Camera {
+	return &game.camera
+}
+
+func (game Game) ScreenToWorld(v Vector2) Vector2 {
+	return game.camera.ScreenToWorld(v)
+}
+
+func (game Game) WorldToScreen(v Vector2) Vector2 {
+	return game.camera.WorldToScreen(v)
+}
@@ -0,0 +1,106 @@
+package raychip
+
+import (
+	"math"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func near(a Vector2, b Vector2) bool {
+	return math.Abs(a.X-b.X) < 1e-9 && math.Abs(a.Y-b.Y) < 1e-9
+}
+
+func TestCameraTransforms(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   Vector2
+		zoom     float64
+		rotation float64
+		world    Vector2
+		screen   Vector2
+	}{
+		{"identity", NewVector2(400, 300), 1, 0, NewVector2(10, 20), NewVector2(10, 20)},
+		{"panned", NewVector2(500, 300), 1, 0, NewVector2(500, 300), NewVector2(400, 300)},
+		{"zoomed", NewVector2(400, 300), 2, 0, NewVector2(410, 300), NewVector2(420, 300)},
+		{"rotated", NewVector2(400, 300), 1, math.Pi / 2, NewVector2(410, 300), NewVector2(400, 310)},
+	}
+	for _, test := range tests {
+		camera := NewCamera(800, 600)
+		camera.SetTarget(test.target)
+		camera.SetZoom(test.zoom)
+		camera.SetRotation(test.rotation)
+		if got := camera.WorldToScreen(test.world); !near(got, test.screen) {
+			t.Errorf("%s: WorldToScreen(%v) = %v, want %v", test.name, test.world, got, test.screen)
+		}
+		if got := camera.ScreenToWorld(test.screen); !near(got, test.world) {
+			t.Errorf("%s: ScreenToWorld(%v) = %v, want %v", test.name, test.screen, got, test.world)
+		}
+	}
+}
+
+func TestCameraDeadzone(t *testing.T) {
+	camera := NewCamera(800, 600)
+	ball := NewCircle(400, 300, 10, rl.Red)
+	camera.Follow(&ball)
+	camera.SetDeadzone(100, 100)
+
+	// inside the deadzone the camera stays put
+	ball.SetPosition(440, 260)
+	camera.update(1.0 / 60)
+	if got := camera.Target(); !near(got, NewVector2(400, 300)) {
+		t.Errorf("camera moved to %v inside the deadzone", got)
+	}
+
+	// past it the camera drags along behind
+	ball.SetPosition(500, 300)
+	camera.update(1.0 / 60)
+	if got := camera.Target(); !near(got, NewVector2(450, 300)) {
+		t.Errorf("camera at %v, want (450, 300)", got)
+	}
+}
+
+func TestCameraBounds(t *testing.T) {
+	camera := NewCamera(800, 600)
+	camera.SetBounds(rl.NewRectangle(0, 0, 1000, 1000))
+
+	camera.SetTarget(NewVector2(-500, 2000))
+	if got := camera.Target(); !near(got, NewVector2(400, 700)) {
+		t.Errorf("target clamped to %v, want (400, 700)", got)
+	}
+
+	// zooming in shrinks the view so it can get closer to the edge
+	camera.SetZoom(2)
+	camera.SetTarget(NewVector2(0, 0))
+	if got := camera.Target(); !near(got, NewVector2(200, 150)) {
+		t.Errorf("zoomed target clamped to %v, want (200, 150)", got)
+	}
+
+	// a view wider than the bounds is centred on them
+	camera.SetZoom(0.5)
+	if got := camera.Target(); !near(got, NewVector2(500, 500)) {
+		t.Errorf("wide view at %v, want (500, 500)", got)
+	}
+}
+
+func TestCameraShake(t *testing.T) {
+	camera := NewCamera(800, 600)
+	camera.Shake(10, 1)
+
+	camera.update(0.5)
+	offset := camera.shakeOffset
+	if offset == (Vector2{}) {
+		t.Errorf("camera didn't shake")
+	}
+	if math.Abs(offset.X) > 5 || math.Abs(offset.Y) > 5 {
+		t.Errorf("shake offset %v is more than half the intensity half way through", offset)
+	}
+	if got := camera.WorldToScreen(NewVector2(400, 300)); !near(got, NewVector2(400+offset.X, 300+offset.Y)) {
+		t.Errorf("WorldToScreen ignores the shake, got %v", got)
+	}
+
+	camera.update(0.5)
+	if camera.shakeOffset != (Vector2{}) {
+		t.Errorf("camera still shaking by %v after the duration", camera.shakeOffset)
+	}
+}
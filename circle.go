@@ -56,30 +56,33 @@ func (p Circle) limitVelocity(body *cp.Body, gravity cp.Vector, damping float64,
 }
 
 func (e *Circle) addToGame(game *Game, args ...any) {
-	if e.physical {
+	if e.physical || e.sensor {
 		if body, ok := args[0].(*cp.Body); ok {
 			if shape, ok := args[1].(*cp.Shape); ok {
 				game.physical = true
-				body = game.space.AddBody(cp.NewBody(e.mass, cp.MomentForCircle(e.mass, 0.0, e.radius, cp.Vector{})))
-				body.SetType(cp.BODY_DYNAMIC)
+				if e.physical {
+					body = game.space.AddBody(cp.NewBody(e.mass, cp.MomentForCircle(e.mass, 0.0, e.radius, cp.Vector{})))
+					body.SetType(cp.BODY_DYNAMIC)
+					body.SetVelocityUpdateFunc(e.limitVelocity)
+				} else {
+					body = game.space.AddBody(newSensorBody())
+				}
 				body.SetPosition(cp.Vector{X: e.position.X, Y: e.position.Y})
 				body.SetVelocity(e.velocity.X, e.velocity.Y)
 				shape = game.space.AddShape(cp.NewCircle(body, e.radius, cp.Vector{}))
 				shape.SetElasticity(e.elasticity)
 				shape.SetFriction(e.friction)
-				body.SetVelocityUpdateFunc(e.limitVelocity)
 				e.cpBody = body
 				e.cpShape = shape
 			}
 		}
 	}
-	e.id = uint64(len(game.entities))
-	game.entities = append(game.entities, e)
+	game.registerEntity(e)
 }
 
 func defaultCircleDrawFunc(p *Circle) {
-	pos := p.Position()
-	rl.DrawCircle(int32(pos.X), int32(pos.Y), float32(p.radius), p.color)
+	pos := p.RenderPosition()
+	p.renderer().DrawCircle(pos, p.radius, p.color)
 }
 
 func (c Circle) DefaultDraw() {
@@ -116,56 +119,28 @@ func (c *Circle) SetUpdateCallback(callback func(*Circle)) {
 	}
 }
 
-func (c *Circle) OnClick(game *Game, button rl.MouseButton, state MouseState, callback func()) int {
-	id := game.EventBus.CreateSubscription("input.mouse", MouseInputEvent{}, func(input MouseInputEvent) {
-		var clicked = false
-		switch state {
-		case MousePressed:
-			if input.IsButtonPressed(button) {
-				clicked = true
-			}
-		case MouseReleased:
-			if input.IsButtonReleased(button) {
-				clicked = true
-			}
-		case MouseUp:
-			if input.IsButtonUp(button) {
-				clicked = true
-			}
-		case MouseDown:
-			if input.IsButtonDown(button) {
-				clicked = true
-			}
-		}
-
-		if clicked {
-			if rl.CheckCollisionPointCircle(game.mousePosition.ToRaylib(), c.position.ToRaylib(), float32(c.radius)) {
-				callback()
-			}
-		}
-	})
+func (c *Circle) OnClick(game *Game, button rl.MouseButton, state MouseState, callback func()) SubscriptionID {
+	return c.onClick(game, button, state, c.CheckMouseCollision, callback)
+}
 
-	return id
+// CheckMouseCollision says whether the mouse, in the coordinates of the
+// circle's layer, is over the circle where it is now
+func (c *Circle) CheckMouseCollision(mousePos Vector2) bool {
+	return rl.CheckCollisionPointCircle(mousePos.ToRaylib(), c.Position().ToRaylib(), float32(c.radius))
 }
 
 func (c *Circle) SetTexture(texture rl.Texture2D) {
-	// not sure if we want to do this?
-	// c.SetDrawCallback(func (c *Circle){
-	// if c.drawCallback != nil {
-	// c.drawCallback(c)
-	// }
-	// })
 	c.SetDrawCallback(func(c *Circle) {
-		pos := c.Position()
-		textureWidth := float32(texture.Width)
-		textureHeight := float32(texture.Height)
-		srcRect := rl.NewRectangle(0, 0, textureWidth, textureHeight)
-		destRect := rl.NewRectangle(float32(pos.X), float32(pos.Y), textureWidth, textureHeight)
-		origin := rl.NewVector2(textureWidth/2, textureHeight/2)
-		angle := float32(c.Angle() * 180.0 / math.Pi)
-		rl.DrawTexturePro(texture, srcRect, destRect, origin, float32(angle), rl.White)
+		c.drawTexture(texture)
 	})
+}
 
+// SetSprite draws the circle as a sprite, which doesn't need to be added to
+// the game itself
+func (c *Circle) SetSprite(sprite *Sprite) {
+	c.SetDrawCallback(func(c *Circle) {
+		sprite.drawAt(c.renderer(), c.RenderPosition(), c.RenderAngle()+sprite.Angle())
+	})
 }
 
 func (p *Circle) Radius() float64 {
@@ -0,0 +1,353 @@
+package raychip
+
+import (
+	"fmt"
+	"math/bits"
+
+	"github.com/jakecoffman/cp/v2"
+)
+
+type ContactPoint struct {
+	// where the contact is on the surface of each entity
+	A        Vector2
+	B        Vector2
+	Distance float64
+}
+
+// Contact describes a collision from the point of view of the first entity,
+// the normal points from A towards B
+type Contact struct {
+	Points  []ContactPoint
+	Normal  Vector2
+	Impulse Vector2 // only set for post-solve
+}
+
+// Collision events are published on the game's EventBus under the topics
+// "collision.begin", "collision.presolve", "collision.postsolve" and
+// "collision.separate".
+type CollisionBegin struct {
+	A       Entity
+	B       Entity
+	Contact Contact
+}
+
+type CollisionPreSolve struct {
+	A       Entity
+	B       Entity
+	Contact Contact
+}
+
+type CollisionPostSolve struct {
+	A       Entity
+	B       Entity
+	Contact Contact
+}
+
+type CollisionSeparate struct {
+	A       Entity
+	B       Entity
+	Contact Contact
+}
+
+// Overlaps involving a sensor are published as trigger events instead, under
+// "trigger.enter", "trigger.stay" and "trigger.exit". Trigger is always the
+// sensor entity.
+type TriggerEnter struct {
+	Trigger Entity
+	Other   Entity
+}
+
+type TriggerStay struct {
+	Trigger Entity
+	Other   Entity
+}
+
+type TriggerExit struct {
+	Trigger Entity
+	Other   Entity
+}
+
+func newContact(arb *cp.Arbiter) Contact {
+	set := arb.ContactPointSet()
+	contact := Contact{
+		Points: make([]ContactPoint, set.Count),
+		Normal: Vector2FromChipmunk(set.Normal),
+	}
+	for i := 0; i < set.Count; i++ {
+		contact.Points[i] = ContactPoint{
+			A:        Vector2FromChipmunk(set.Points[i].PointA),
+			B:        Vector2FromChipmunk(set.Points[i].PointB),
+			Distance: set.Points[i].Distance,
+		}
+	}
+	return contact
+}
+
+// flipped is the same contact seen from entity B
+func (c Contact) flipped() Contact {
+	out := Contact{
+		Points:  make([]ContactPoint, len(c.Points)),
+		Normal:  NewVector2(-c.Normal.X, -c.Normal.Y),
+		Impulse: NewVector2(-c.Impulse.X, -c.Impulse.Y),
+	}
+	for i, p := range c.Points {
+		out.Points[i] = ContactPoint{A: p.B, B: p.A, Distance: p.Distance}
+	}
+	return out
+}
+
+func arbiterEntities(arb *cp.Arbiter) (Entity, Entity, bool) {
+	shapeA, shapeB := arb.Shapes()
+	a, okA := shapeA.UserData.(Entity)
+	b, okB := shapeB.UserData.(Entity)
+	return a, b, okA && okB
+}
+
+func arbiterSensors(arb *cp.Arbiter) (bool, bool) {
+	shapeA, shapeB := arb.Shapes()
+	return shapeA.Sensor(), shapeB.Sensor()
+}
+
+func publishIfSubscribed(bus *EventBus, topicName string, msg any) {
+	if bus.hasSubscribers(topicName, msg) {
+		bus.Publish(topicName, msg)
+	}
+}
+
+// collisionPair is the two entities in a collision, if the wildcard callback
+// should publish for it. Shapes made by raychip all have collision type 0, so
+// two entities touching reach the wildcard handler twice, once from each side,
+// and only the side with the lower id publishes.
+func collisionPair(arb *cp.Arbiter) (Entity, Entity, bool) {
+	a, b, ok := arbiterEntities(arb)
+	if !ok || a.Id() > b.Id() {
+		return nil, nil, false
+	}
+	return a, b, true
+}
+
+// installCollisionHandler hooks chipmunk's collision callbacks up to the
+// EventBus. It is a wildcard handler so it hears about every collision
+// involving an entity, whatever the other shape's collision type. The game is
+// copied out of NewGame, so Update points the handler's UserData at it before
+// stepping rather than the handler keeping a pointer.
+func (game *Game) installCollisionHandler() {
+	handler := game.space.NewWildcardCollisionHandler(0)
+
+	handler.BeginFunc = func(arb *cp.Arbiter, space *cp.Space, data interface{}) bool {
+		bus := &data.(*Game).EventBus
+		if a, b, ok := collisionPair(arb); ok {
+			if aSensor, bSensor := arbiterSensors(arb); aSensor || bSensor {
+				if aSensor {
+					publishIfSubscribed(bus, "trigger.enter", TriggerEnter{Trigger: a, Other: b})
+				}
+				if bSensor {
+					publishIfSubscribed(bus, "trigger.enter", TriggerEnter{Trigger: b, Other: a})
+				}
+			} else if bus.hasSubscribers("collision.begin", CollisionBegin{}) {
+				bus.Publish("collision.begin", CollisionBegin{A: a, B: b, Contact: newContact(arb)})
+			}
+		}
+		return true
+	}
+
+	handler.PreSolveFunc = func(arb *cp.Arbiter, space *cp.Space, data interface{}) bool {
+		bus := &data.(*Game).EventBus
+		if a, b, ok := collisionPair(arb); ok {
+			if aSensor, bSensor := arbiterSensors(arb); aSensor || bSensor {
+				if aSensor {
+					publishIfSubscribed(bus, "trigger.stay", TriggerStay{Trigger: a, Other: b})
+				}
+				if bSensor {
+					publishIfSubscribed(bus, "trigger.stay", TriggerStay{Trigger: b, Other: a})
+				}
+			} else if bus.hasSubscribers("collision.presolve", CollisionPreSolve{}) {
+				bus.Publish("collision.presolve", CollisionPreSolve{A: a, B: b, Contact: newContact(arb)})
+			}
+		}
+		return true
+	}
+
+	handler.PostSolveFunc = func(arb *cp.Arbiter, space *cp.Space, data interface{}) {
+		bus := &data.(*Game).EventBus
+		if a, b, ok := collisionPair(arb); ok && bus.hasSubscribers("collision.postsolve", CollisionPostSolve{}) {
+			contact := newContact(arb)
+			contact.Impulse = Vector2FromChipmunk(arb.TotalImpulse())
+			bus.Publish("collision.postsolve", CollisionPostSolve{A: a, B: b, Contact: contact})
+		}
+	}
+
+	handler.SeparateFunc = func(arb *cp.Arbiter, space *cp.Space, data interface{}) {
+		bus := &data.(*Game).EventBus
+		if a, b, ok := collisionPair(arb); ok {
+			if aSensor, bSensor := arbiterSensors(arb); aSensor || bSensor {
+				if aSensor {
+					publishIfSubscribed(bus, "trigger.exit", TriggerExit{Trigger: a, Other: b})
+				}
+				if bSensor {
+					publishIfSubscribed(bus, "trigger.exit", TriggerExit{Trigger: b, Other: a})
+				}
+			} else if bus.hasSubscribers("collision.separate", CollisionSeparate{}) {
+				bus.Publish("collision.separate", CollisionSeparate{A: a, B: b, Contact: newContact(arb)})
+			}
+		}
+	}
+
+	game.collisionHandler = handler
+}
+
+// OnCollision calls back whenever this entity starts touching another one.
+// The contact is given from this entity's point of view.
+func (e *EntityBase) OnCollision(game *Game, callback func(other Entity, contact Contact)) SubscriptionID {
+	id := Subscribe(&game.EventBus, "collision.begin", func(event CollisionBegin) {
+		if !e.inGame(game) {
+			return
+		}
+		if event.A.base() == e {
+			callback(event.B, event.Contact)
+		} else if event.B.base() == e {
+			callback(event.A, event.Contact.flipped())
+		}
+	})
+
+	return e.unsubscribeOnRemove(game, id)
+}
+
+// OnSeparate calls back whenever this entity stops touching another one
+func (e *EntityBase) OnSeparate(game *Game, callback func(other Entity)) SubscriptionID {
+	id := Subscribe(&game.EventBus, "collision.separate", func(event CollisionSeparate) {
+		if !e.inGame(game) {
+			return
+		}
+		if event.A.base() == e {
+			callback(event.B)
+		} else if event.B.base() == e {
+			callback(event.A)
+		}
+	})
+
+	return e.unsubscribeOnRemove(game, id)
+}
+
+// OnTriggerEnter calls back when another entity starts overlapping this
+// sensor entity
+func (e *EntityBase) OnTriggerEnter(game *Game, callback func(other Entity)) SubscriptionID {
+	id := Subscribe(&game.EventBus, "trigger.enter", func(event TriggerEnter) {
+		if !e.inGame(game) {
+			return
+		}
+		if event.Trigger.base() == e {
+			callback(event.Other)
+		}
+	})
+
+	return e.unsubscribeOnRemove(game, id)
+}
+
+// OnTriggerStay calls back every step another entity overlaps this sensor
+// entity
+func (e *EntityBase) OnTriggerStay(game *Game, callback func(other Entity)) SubscriptionID {
+	id := Subscribe(&game.EventBus, "trigger.stay", func(event TriggerStay) {
+		if !e.inGame(game) {
+			return
+		}
+		if event.Trigger.base() == e {
+			callback(event.Other)
+		}
+	})
+
+	return e.unsubscribeOnRemove(game, id)
+}
+
+func (e *EntityBase) OnTriggerExit(game *Game, callback func(other Entity)) SubscriptionID {
+	id := Subscribe(&game.EventBus, "trigger.exit", func(event TriggerExit) {
+		if !e.inGame(game) {
+			return
+		}
+		if event.Trigger.base() == e {
+			callback(event.Other)
+		}
+	})
+
+	return e.unsubscribeOnRemove(game, id)
+}
+
+// CollisionLayer gives the category bit for a named layer such as "player" or
+// "enemy", registering the layer the first time the name is used
+func (game *Game) CollisionLayer(name string) uint {
+	if game.collisionLayers == nil {
+		game.collisionLayers = make(map[string]uint)
+	}
+	if category, ok := game.collisionLayers[name]; ok {
+		return category
+	}
+	if len(game.collisionLayers) >= bits.UintSize {
+		panic(fmt.Sprintf("cannot register collision layer %s, all %d layers are in use", name, bits.UintSize))
+	}
+	category := uint(1) << len(game.collisionLayers)
+	game.collisionLayers[name] = category
+	return category
+}
+
+// CollisionLayers combines several named layers into one bitmask
+func (game *Game) CollisionLayers(names ...string) uint {
+	var mask uint
+	for _, name := range names {
+		mask |= game.CollisionLayer(name)
+	}
+	return mask
+}
+
+// CollisionMaskExcept is a mask that collides with every layer but the named ones
+func (game *Game) CollisionMaskExcept(names ...string) uint {
+	return ^game.CollisionLayers(names...)
+}
+
+func (e *EntityBase) shapeFilter() cp.ShapeFilter {
+	if e.filter == nil {
+		return cp.SHAPE_FILTER_ALL
+	}
+	return *e.filter
+}
+
+func (e *EntityBase) setShapeFilter(filter cp.ShapeFilter) {
+	e.filter = &filter
+	e.eachShape(func(shape *cp.Shape) {
+		shape.SetFilter(filter)
+	})
+}
+
+// SetCollisionCategory sets the layers the entity belongs to
+func (e *EntityBase) SetCollisionCategory(categories uint) {
+	filter := e.shapeFilter()
+	filter.Categories = categories
+	e.setShapeFilter(filter)
+}
+
+func (e *EntityBase) CollisionCategory() uint {
+	return e.shapeFilter().Categories
+}
+
+// SetCollisionMask sets the layers the entity collides with. Both entities'
+// masks have to include the other's category for them to collide.
+func (e *EntityBase) SetCollisionMask(mask uint) {
+	filter := e.shapeFilter()
+	filter.Mask = mask
+	e.setShapeFilter(filter)
+}
+
+func (e *EntityBase) CollisionMask() uint {
+	return e.shapeFilter().Mask
+}
+
+// SetCollisionGroup puts the entity in a group, entities sharing the same
+// non-zero group never collide with each other
+func (e *EntityBase) SetCollisionGroup(group uint) {
+	filter := e.shapeFilter()
+	filter.Group = group
+	e.setShapeFilter(filter)
+}
+
+func (e *EntityBase) CollisionGroup() uint {
+	return e.shapeFilter().Group
+}
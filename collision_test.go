@@ -0,0 +1,195 @@
+package raychip
+
+import (
+	"fmt"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func TestCollisionEvents(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	game.SetGravity(NewVector2(0, 1000))
+	floor := NewWall(NewVector2(0, 300), NewVector2(800, 300), 2, rl.Black)
+	game.AddEntity(&floor)
+	ball := NewPhysicalCircle(400, 200, 10, 1, rl.Red)
+	ball.SetElasticity(0.9)
+	game.AddEntity(&ball)
+
+	var others []Entity
+	var contacts []Contact
+	ball.OnCollision(&game, func(other Entity, contact Contact) {
+		others = append(others, other)
+		contacts = append(contacts, contact)
+	})
+	begins, separates, postSolves := 0, 0, 0
+	var impulse Vector2
+	game.EventBus.CreateSubscription("collision.begin", CollisionBegin{}, func(CollisionBegin) { begins++ })
+	game.EventBus.CreateSubscription("collision.separate", CollisionSeparate{}, func(CollisionSeparate) { separates++ })
+	game.EventBus.CreateSubscription("collision.postsolve", CollisionPostSolve{}, func(event CollisionPostSolve) {
+		postSolves++
+		impulse = event.Contact.Impulse
+	})
+
+	// long enough to hit the floor and bounce off it once
+	for i := 0; i < 120 && separates == 0; i++ {
+		game.RunFrames(1)
+	}
+
+	if begins != 1 || len(others) != 1 {
+		t.Fatalf("got %d begin events and %d OnCollision calls, want 1 of each", begins, len(others))
+	}
+	if others[0] != &floor {
+		t.Errorf("ball collided with %v, want the floor", others[0])
+	}
+	// seen from the ball, the normal points down into the floor
+	if n := contacts[0].Normal; n.Y <= 0 || len(contacts[0].Points) == 0 {
+		t.Errorf("contact from the ball's side is %+v", contacts[0])
+	}
+	if separates != 1 {
+		t.Errorf("got %d separate events, want 1", separates)
+	}
+	if postSolves == 0 || impulse == (Vector2{}) {
+		t.Errorf("got %d post-solve events, last impulse %v", postSolves, impulse)
+	}
+}
+
+func TestCollisionLayers(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	player, enemy := game.CollisionLayer("player"), game.CollisionLayer("enemy")
+	if player == enemy || player&enemy != 0 {
+		t.Errorf("layers share bits: player %b, enemy %b", player, enemy)
+	}
+	if game.CollisionLayer("player") != player {
+		t.Error("looking a layer up again gave a different bit")
+	}
+	if mask := game.CollisionMaskExcept("player"); mask&player != 0 || mask&enemy == 0 {
+		t.Errorf("mask except player is %b", mask)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering more layers than bits didn't panic")
+		}
+	}()
+	for i := 0; ; i++ {
+		game.CollisionLayer(fmt.Sprint("layer", i))
+	}
+}
+
+// dropOnFloor drops a ball onto a floor and says where it ended up
+func dropOnFloor(setup func(game *Game, floor *Wall, ball *Circle)) float64 {
+	game := NewHeadlessGame(800, 600, 60)
+	game.SetGravity(NewVector2(0, 1000))
+	floor := NewWall(NewVector2(0, 300), NewVector2(800, 300), 2, rl.Black)
+	ball := NewPhysicalCircle(400, 250, 10, 1, rl.Red)
+	setup(&game, &floor, &ball)
+	game.AddEntity(&floor)
+	game.AddEntity(&ball)
+	game.RunFrames(60)
+	return ball.Position().Y
+}
+
+func TestCollisionFilters(t *testing.T) {
+	if y := dropOnFloor(func(game *Game, floor *Wall, ball *Circle) {}); y > 300 {
+		t.Fatalf("unfiltered ball fell through the floor to %v", y)
+	}
+
+	// set before the ball is added
+	if y := dropOnFloor(func(game *Game, floor *Wall, ball *Circle) {
+		floor.SetCollisionCategory(game.CollisionLayer("world"))
+		ball.SetCollisionMask(game.CollisionMaskExcept("world"))
+	}); y < 300 {
+		t.Errorf("masked ball landed on the floor at %v", y)
+	}
+
+	if y := dropOnFloor(func(game *Game, floor *Wall, ball *Circle) {
+		floor.SetCollisionGroup(1)
+		ball.SetCollisionGroup(1)
+	}); y < 300 {
+		t.Errorf("ball landed on a floor in its own group at %v", y)
+	}
+
+	if y := dropOnFloor(func(game *Game, floor *Wall, ball *Circle) {
+		floor.SetCollisionGroup(1)
+		ball.SetCollisionGroup(2)
+	}); y > 300 {
+		t.Errorf("ball fell through a floor in another group to %v", y)
+	}
+}
+
+func TestCollisionMaskAfterAdd(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	game.SetGravity(NewVector2(0, 1000))
+	floor := NewWall(NewVector2(0, 300), NewVector2(800, 300), 2, rl.Black)
+	game.AddEntity(&floor)
+	ball := NewPhysicalCircle(400, 250, 10, 1, rl.Red)
+	game.AddEntity(&ball)
+
+	floor.SetCollisionCategory(game.CollisionLayer("world"))
+	ball.SetCollisionMask(game.CollisionMaskExcept("world"))
+	game.RunFrames(60)
+	if y := ball.Position().Y; y < 300 {
+		t.Errorf("ball masked after being added landed on the floor at %v", y)
+	}
+}
+
+func TestTriggerEvents(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	game.SetGravity(NewVector2(0, 1000))
+	zone := NewBox(400, 300, 200, 40, rl.Green)
+	zone.SetSensor(true)
+	game.AddEntity(&zone)
+	ball := NewPhysicalCircle(400, 200, 10, 1, rl.Red)
+	game.AddEntity(&ball)
+
+	var events []string
+	zone.OnTriggerEnter(&game, func(other Entity) {
+		if other == &ball {
+			events = append(events, "enter")
+		}
+	})
+	stays := 0
+	zone.OnTriggerStay(&game, func(other Entity) { stays++ })
+	zone.OnTriggerExit(&game, func(other Entity) {
+		if other == &ball {
+			events = append(events, "exit")
+		}
+	})
+
+	game.RunFrames(60)
+
+	if len(events) != 2 || events[0] != "enter" || events[1] != "exit" {
+		t.Errorf("got trigger events %v, want enter then exit", events)
+	}
+	if stays == 0 {
+		t.Error("no trigger stay events while the ball was inside")
+	}
+	// sensors don't push back
+	if y := ball.Position().Y; y < 340 {
+		t.Errorf("ball was stopped by the sensor at %v", y)
+	}
+}
+
+func TestSensorSeesStaticAndSensors(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	wall := NewWall(NewVector2(0, 300), NewVector2(800, 300), 2, rl.Black)
+	game.AddEntity(&wall)
+	zone := NewBox(400, 300, 40, 40, rl.Green)
+	zone.SetSensor(true)
+	game.AddEntity(&zone)
+	other := NewCircle(410, 300, 10, rl.Green)
+	other.SetSensor(true)
+	game.AddEntity(&other)
+
+	var seen []Entity
+	zone.OnTriggerEnter(&game, func(e Entity) { seen = append(seen, e) })
+	game.RunFrames(2)
+
+	if len(seen) != 2 {
+		t.Errorf("non-physical sensor saw %d entities, want the wall and the other sensor", len(seen))
+	}
+	if zone.Position() != NewVector2(400, 300) {
+		t.Errorf("non-physical sensor moved to %v", zone.Position())
+	}
+}
@@ -11,6 +11,7 @@ type Entity interface {
 	Draw()
 	Id() uint64
 	addToGame(game *Game, args ...any)
+	base() *EntityBase
 }
 
 type EntityBase struct {
@@ -18,7 +19,9 @@ type EntityBase struct {
 	angle       float64
 	color       rl.Color
 	id          uint64
+	generation  uint32
 	physical    bool
+	sensor      bool
 	velocity    Vector2
 	velocityMax float64
 	mass        float64
@@ -26,12 +29,45 @@ type EntityBase struct {
 	friction    float64
 	cpBody      *cp.Body
 	cpShape     *cp.Shape
+	extraShapes []*cp.Shape
+	filter      *cp.ShapeFilter
+	game        *Game
+	layer       string
+	z           float64
+	animator    *Animator
+
+	removedCallbacks []func()
+	subscriptions    []entitySubscription
+
+	// state before the latest physics step, used for interpolation
+	prevPosition Vector2
+	prevAngle    float64
+}
+
+// EntityHandle refers to an entity for as long as it stays in a game. It goes
+// stale when the entity is removed, even if the entity is added back later.
+type EntityHandle struct {
+	ID         uint64
+	Generation uint32
 }
 
 func (e EntityBase) Id() uint64 {
 	return e.id
 }
 
+// Generation counts how many times the entity has been added to its game
+func (e EntityBase) Generation() uint32 {
+	return e.generation
+}
+
+func (e EntityBase) Handle() EntityHandle {
+	return EntityHandle{ID: e.id, Generation: e.generation}
+}
+
+func (e *EntityBase) base() *EntityBase {
+	return e
+}
+
 func (e *EntityBase) SetColor(color rl.Color) {
 	e.color = color
 }
@@ -44,6 +80,29 @@ func (e EntityBase) IsPhysical() bool {
 	return e.physical
 }
 
+// SetSensor makes the entity a trigger that reports overlaps without pushing
+// anything. Non-physical entities only get a collision shape if they are
+// made sensors before being added to the game.
+func (e *EntityBase) SetSensor(sensor bool) {
+	e.sensor = sensor
+	e.eachShape(func(shape *cp.Shape) {
+		shape.SetSensor(sensor)
+	})
+}
+
+func (e EntityBase) IsSensor() bool {
+	return e.sensor
+}
+
+// newSensorBody is the body for a sensor that isn't physical. Chipmunk never
+// checks kinematic bodies against static or other kinematic ones, so it is a
+// dynamic body that ignores gravity and damping, and only moves when told to.
+func newSensorBody() *cp.Body {
+	body := cp.NewBody(1, cp.INFINITY)
+	body.SetVelocityUpdateFunc(func(body *cp.Body, gravity cp.Vector, damping float64, dt float64) {})
+	return body
+}
+
 func (e *EntityBase) SetMass(m float64) {
 	e.mass = m
 	if e.cpBody != nil {
@@ -60,9 +119,9 @@ func (e *EntityBase) Mass() float64 {
 
 func (e *EntityBase) SetElasticity(elasticity float64) {
 	e.elasticity = elasticity
-	if e.cpShape != nil {
-		e.cpShape.SetElasticity(elasticity)
-	}
+	e.eachShape(func(shape *cp.Shape) {
+		shape.SetElasticity(elasticity)
+	})
 }
 
 func (e *EntityBase) Elasticity() float64 {
@@ -74,9 +133,9 @@ func (e *EntityBase) Elasticity() float64 {
 
 func (e *EntityBase) SetFriction(f float64) {
 	e.friction = f
-	if e.cpShape != nil {
-		e.cpShape.SetFriction(f)
-	}
+	e.eachShape(func(shape *cp.Shape) {
+		shape.SetFriction(f)
+	})
 }
 
 func (e *EntityBase) Friction() float64 {
@@ -91,6 +150,7 @@ func (e *EntityBase) SetAngle(a float64) {
 	if e.cpBody != nil {
 		e.cpBody.SetAngle(a)
 	}
+	e.prevAngle = a
 }
 
 func (e *EntityBase) Angle() float64 {
@@ -117,8 +177,10 @@ func (e *EntityBase) SetPosition(x float64, y float64) {
 	e.position.X = x
 	e.position.Y = y
 	if e.cpBody != nil {
-		e.cpBody.SetPosition(e.Position().ToChipmunk())
+		e.cpBody.SetPosition(e.position.ToChipmunk())
 	}
+	// teleporting shouldn't be smoothed over
+	e.prevPosition = e.position
 }
 
 func (e *EntityBase) Position() Vector2 {
@@ -150,3 +212,171 @@ func (e *EntityBase) SetVelocityMax(v float64) {
 func (e *EntityBase) VelocityMax() float64 {
 	return e.velocityMax
 }
+
+func (e *EntityBase) savePhysicsState() {
+	e.prevPosition = e.Position()
+	e.prevAngle = e.Angle()
+}
+
+// InterpolatedPosition blends between the position before the latest physics
+// step (alpha = 0) and the current position (alpha = 1)
+func (e *EntityBase) InterpolatedPosition(alpha float64) Vector2 {
+	pos := e.Position()
+	return NewVector2(
+		e.prevPosition.X+(pos.X-e.prevPosition.X)*alpha,
+		e.prevPosition.Y+(pos.Y-e.prevPosition.Y)*alpha,
+	)
+}
+
+func (e *EntityBase) InterpolatedAngle(alpha float64) float64 {
+	angle := e.Angle()
+	return e.prevAngle + (angle-e.prevAngle)*alpha
+}
+
+// RenderPosition is the position to draw the entity at, interpolated with the
+// game's current alpha for physical entities
+func (e *EntityBase) RenderPosition() Vector2 {
+	if e.game == nil || e.cpBody == nil {
+		return e.Position()
+	}
+	return e.InterpolatedPosition(e.game.Alpha())
+}
+
+func (e *EntityBase) RenderAngle() float64 {
+	if e.game == nil || e.cpBody == nil {
+		return e.Angle()
+	}
+	return e.InterpolatedAngle(e.game.Alpha())
+}
+
+// OnRemoved registers a callback to run when the entity is removed from its game
+func (e *EntityBase) OnRemoved(callback func()) {
+	e.removedCallbacks = append(e.removedCallbacks, callback)
+}
+
+type entitySubscription struct {
+	game *Game
+	id   SubscriptionID
+}
+
+// unsubscribeOnRemove remembers a subscription made on the entity's behalf,
+// such as by OnClick, so it can be cancelled when the entity is removed with
+// RemoveEntity. ClearEntities keeps them, so an entity that comes back in a
+// later scene still has its handlers.
+func (e *EntityBase) unsubscribeOnRemove(game *Game, id SubscriptionID) SubscriptionID {
+	e.subscriptions = append(e.subscriptions, entitySubscription{game: game, id: id})
+	return id
+}
+
+// inGame is false while the entity is out of the game, the handlers kept
+// across ClearEntities check it so they don't fire for entities that aren't
+// there
+func (e *EntityBase) inGame(game *Game) bool {
+	registered, ok := game.registry[e.id]
+	return ok && registered.base() == e
+}
+
+func (e *EntityBase) unsubscribeAll(game *Game) {
+	var kept []entitySubscription
+	for _, sub := range e.subscriptions {
+		if sub.game == game {
+			game.EventBus.Unsubscribe(sub.id)
+		} else {
+			kept = append(kept, sub)
+		}
+	}
+	e.subscriptions = kept
+}
+
+func (e *EntityBase) eachShape(f func(*cp.Shape)) {
+	if e.cpShape != nil {
+		f(e.cpShape)
+	}
+	for _, shape := range e.extraShapes {
+		f(shape)
+	}
+}
+
+// removeFromSpace takes the entity's shapes and body out of the physics space.
+// Chipmunk doesn't allow that while the space is stepping, so when called from
+// a collision callback the removal waits for a post-step callback instead.
+func (e *EntityBase) removeFromSpace(game *Game) {
+	if e.cpBody == nil && e.cpShape == nil {
+		return
+	}
+
+	// keep the last physics state around in case the entity is added again
+	e.Position()
+	e.Velocity()
+	e.Angle()
+	e.Mass()
+	e.Elasticity()
+	e.Friction()
+
+	var shapes []*cp.Shape
+	e.eachShape(func(shape *cp.Shape) {
+		shapes = append(shapes, shape)
+	})
+	body := e.cpBody
+	e.cpBody = nil
+	e.cpShape = nil
+	e.extraShapes = nil
+
+	space := game.space
+	remove := func() {
+		for _, shape := range shapes {
+			if space.ContainsShape(shape) {
+				space.RemoveShape(shape)
+			}
+		}
+		if body != nil && space.ContainsBody(body) {
+			space.RemoveBody(body)
+		}
+	}
+
+	if game.stepping {
+		space.AddPostStepCallback(func(*cp.Space, any, any) {
+			remove()
+		}, e, nil)
+	} else {
+		remove()
+	}
+}
+
+// onClick is OnClick for every kind of entity, hit says whether the mouse is
+// over the entity
+func (e *EntityBase) onClick(game *Game, button rl.MouseButton, state MouseState, hit func(mousePos Vector2) bool, callback func()) SubscriptionID {
+	id := Subscribe(&game.EventBus, "input.mouse", func(input MouseInputEvent) {
+		if !e.inGame(game) {
+			return
+		}
+		var clicked = false
+		switch state {
+		case MousePressed:
+			clicked = input.IsButtonPressed(button)
+		case MouseReleased:
+			clicked = input.IsButtonReleased(button)
+		case MouseUp:
+			clicked = input.IsButtonUp(button)
+		case MouseDown:
+			clicked = input.IsButtonDown(button)
+		}
+
+		if clicked && hit(e.mousePosition(game)) {
+			callback()
+		}
+	})
+
+	return e.unsubscribeOnRemove(game, id)
+}
+
+// drawTexture draws a whole texture centred on the entity, for SetTexture
+func (e *EntityBase) drawTexture(texture rl.Texture2D) {
+	pos := e.RenderPosition()
+	textureWidth := float32(texture.Width)
+	textureHeight := float32(texture.Height)
+	srcRect := rl.NewRectangle(0, 0, textureWidth, textureHeight)
+	destRect := rl.NewRectangle(float32(pos.X), float32(pos.Y), textureWidth, textureHeight)
+	origin := NewVector2(float64(textureWidth)/2, float64(textureHeight)/2)
+	e.renderer().DrawTexture(texture, srcRect, destRect, origin, e.RenderAngle(), rl.White)
+}
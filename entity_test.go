@@ -0,0 +1,172 @@
+package raychip
+
+import (
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/jakecoffman/cp/v2"
+)
+
+func TestRemoveEntityDetachesPhysics(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	ball := NewPhysicalCircle(100, 100, 10, 1, rl.Red)
+	box := NewPhysicalBox(200, 100, 10, 10, 1, rl.Red)
+	game.AddEntity(&ball)
+	game.AddEntity(&box)
+	ballBody, ballShape := ball.cpBody, ball.cpShape
+	removed := 0
+	ball.OnRemoved(func() { removed++ })
+
+	game.RemoveEntity(&ball)
+	if game.space.ContainsBody(ballBody) || game.space.ContainsShape(ballShape) {
+		t.Error("removed ball is still in the space")
+	}
+	if removed != 1 {
+		t.Errorf("OnRemoved ran %d times, want 1", removed)
+	}
+
+	boxBody := box.cpBody
+	game.ClearEntities()
+	if game.space.ContainsBody(boxBody) {
+		t.Error("cleared box is still in the space")
+	}
+
+	// the entity keeps its state and can be added again
+	game.AddEntity(&ball)
+	if pos := ball.Position(); pos != NewVector2(100, 100) {
+		t.Errorf("re-added ball is at %v", pos)
+	}
+	if !game.space.ContainsBody(ball.cpBody) {
+		t.Error("re-added ball has no body in the space")
+	}
+}
+
+func TestRemoveEntityMidStep(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	game.SetGravity(NewVector2(0, 1000))
+	floor := NewWall(NewVector2(0, 300), NewVector2(800, 300), 2, rl.Black)
+	game.AddEntity(&floor)
+	ball := NewPhysicalCircle(400, 250, 10, 1, rl.Red)
+	game.AddEntity(&ball)
+	body := ball.cpBody
+
+	// remove the ball from inside chipmunk's step, the way a collision
+	// callback would
+	hits := 0
+	handler := game.space.NewCollisionHandler(0, 0)
+	handler.BeginFunc = func(arb *cp.Arbiter, space *cp.Space, data any) bool {
+		hits++
+		game.RemoveEntity(&ball)
+		return false
+	}
+
+	game.RunFrames(60)
+
+	if hits != 1 {
+		t.Errorf("ball hit the floor %d times, want 1", hits)
+	}
+	if game.space.ContainsBody(body) {
+		t.Error("ball's body is still in the space")
+	}
+	if game.EntitiesCount() != 1 {
+		t.Errorf("game has %d entities, want 1", game.EntitiesCount())
+	}
+}
+
+func TestMouseCollisionFollowsBody(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	game.SetGravity(NewVector2(0, 600))
+	box := NewPhysicalBox(100, 100, 20, 20, 1, rl.Red)
+	ball := NewPhysicalCircle(200, 100, 10, 1, rl.Red)
+	game.AddEntity(&box)
+	game.AddEntity(&ball)
+
+	game.RunFrames(30)
+
+	if box.CheckMouseCollision(NewVector2(100, 100)) {
+		t.Errorf("box still hit where it was added")
+	}
+	if !box.CheckMouseCollision(box.Position()) {
+		t.Errorf("box not hit where it fell to")
+	}
+	if ball.CheckMouseCollision(NewVector2(200, 100)) {
+		t.Errorf("ball still hit where it was added")
+	}
+	if !ball.CheckMouseCollision(ball.Position()) {
+		t.Errorf("ball not hit where it fell to")
+	}
+}
+
+func TestClickSurvivesSceneChange(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	game.EnableMouseInput()
+	ball := NewCircle(300, 300, 30, rl.Black)
+	menu, level := NewScene(), NewScene()
+	level.AddEntity(&ball)
+	clicks := 0
+	if err := game.SetScene(level); err != nil {
+		t.Fatal(err)
+	}
+	ball.OnClick(&game, rl.MouseButtonLeft, MouseReleased, func() { clicks++ })
+	input := game.InputSource().(*ScriptedInput)
+
+	if err := game.SetScene(menu); err != nil {
+		t.Fatal(err)
+	}
+	input.ClickAt(300, 300, rl.MouseButtonLeft)
+	game.RunFrames(2)
+	if clicks != 0 {
+		t.Errorf("ball was clicked %d times while out of the game", clicks)
+	}
+
+	if err := game.SetScene(level); err != nil {
+		t.Fatal(err)
+	}
+	input.ClickAt(300, 300, rl.MouseButtonLeft)
+	game.RunFrames(2)
+	if clicks != 1 {
+		t.Errorf("ball was clicked %d times after coming back, want 1", clicks)
+	}
+
+	game.RemoveEntity(&ball)
+	game.AddEntity(&ball)
+	input.ClickAt(300, 300, rl.MouseButtonLeft)
+	game.RunFrames(2)
+	if clicks != 1 {
+		t.Errorf("handler outlived RemoveEntity, ball clicked %d times", clicks)
+	}
+}
+
+func TestRemoveClearedEntity(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	game.SetGravity(NewVector2(0, 600))
+	ball := NewPhysicalCircle(100, 100, 10, 1, rl.Red)
+	wall := NewPhysicalBox(100, 200, 100, 20, 1, rl.Black)
+	wall.Fix()
+	game.AddEntity(&ball)
+	game.AddEntity(&wall)
+	collisions := 0
+	ball.OnCollision(&game, func(Entity, Contact) { collisions++ })
+	ball.OnClick(&game, rl.MouseButtonLeft, MousePressed, func() {})
+
+	game.ClearEntities()
+	if len(ball.subscriptions) != 2 {
+		t.Fatalf("cleared ball has %d subscriptions, want 2", len(ball.subscriptions))
+	}
+
+	// a cleared entity can still be removed for good
+	game.RemoveEntity(&ball)
+	if _, ok := game.detached[ball.Id()]; ok || len(ball.subscriptions) != 0 {
+		t.Errorf("removed ball kept %d subscriptions", len(ball.subscriptions))
+	}
+
+	game.AddEntity(&ball)
+	game.AddEntity(&wall)
+	if len(game.detached) != 0 {
+		t.Errorf("game kept %d detached entities after adding them back", len(game.detached))
+	}
+	game.RunFrames(60)
+	if collisions != 0 {
+		t.Errorf("collision handler outlived RemoveEntity, called %d times", collisions)
+	}
+}
@@ -1,32 +1,93 @@
 package raychip
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 )
 
+// Topics form a hierarchy split on dots, so a message published on
+// "collision.player.enemy" reaches subscribers of "collision.player" and
+// "collision" as well. Subscriptions can also use patterns where "*" matches
+// any one part of a topic and "**" matches one or more parts, so "input.*"
+// gets "input.mouse" but not "input.gamepad.connection" and "collision.**"
+// gets everything under "collision". Patterns aren't extended to the topics
+// below what they match.
 type Topic struct {
 	name string
 	typ  reflect.Type
 }
 
+// SubscriptionID identifies a subscription on its bus. Ids are never reused
+// and zero is never a valid id.
+type SubscriptionID uint64
+
 type Subscription struct {
 	function func(any)
-	id       int
+	id       SubscriptionID
+	priority int
 	active   bool
+	removed  bool
+	once     bool
+}
+
+type queuedMessage struct {
+	topic Topic
+	msg   any
 }
 
+// EventBus is safe to use from any goroutine, but callbacks run on whichever
+// goroutine delivers the message. A game's bus queues anything published from
+// a goroutine other than the game loop's until the loop calls Dispatch, so
+// its callbacks can safely call raylib. Other buses can use Enqueue, or be put
+// in queued mode, to get the same.
 type EventBus struct {
-	subscriptions map[Topic][]Subscription
+	// mu guards everything below and is never held while a callback runs. It
+	// is a pointer so buses, and the games holding them, can still be copied.
+	mu *sync.Mutex
+
+	subscriptions map[Topic][]*Subscription
+	topics        map[SubscriptionID]Topic
+	nextID        SubscriptionID
+	// topics with a pattern or an interface type, which have to be checked
+	// against every message
+	wildcards map[Topic]bool
+	// removals are held back until every delivery has finished
+	publishing int
+	pending    bool
+	// the messages each goroutine is part way through delivering, innermost
+	// last, for StopPropagation
+	deliveries map[int64][]*delivery
+
+	queued bool
+	queue  []queuedMessage
+	strict bool
+	// owner is the goroutine that delivers straight away when the bus isn't
+	// queued, others have their messages queued. Zero lets any goroutine.
+	owner int64
+}
+
+// delivery is the state of one message being delivered
+type delivery struct {
+	stopped bool
 }
 
 func NewEventBus() EventBus {
 	return EventBus{
-		subscriptions: make(map[Topic][]Subscription),
+		mu:            new(sync.Mutex),
+		subscriptions: make(map[Topic][]*Subscription),
+		topics:        make(map[SubscriptionID]Topic),
+		wildcards:     make(map[Topic]bool),
+		deliveries:    make(map[int64][]*delivery),
 	}
 }
 
-func (bus *EventBus) CreateSubscription(topicName string, msgType any, callback any) int {
+func (bus *EventBus) CreateSubscription(topicName string, msgType any, callback any) SubscriptionID {
 	typ := reflect.TypeOf(msgType)
 
 	if typ == nil || typ.Kind() == reflect.Pointer {
@@ -49,82 +110,487 @@ func (bus *EventBus) CreateSubscription(topicName string, msgType any, callback
 		callbackValue.Call([]reflect.Value{reflect.ValueOf(msg)})
 	}
 
-	id := len(bus.subscriptions[topic])
-	sub := Subscription{
-		function: wrappedCallback,
-		id:       len(bus.subscriptions[topic]),
+	return bus.subscribe(topic, wrappedCallback, false)
+}
+
+// Subscribe is a type safe CreateSubscription. The message type comes from
+// the callback and messages are passed to it without reflection.
+func Subscribe[T any](bus *EventBus, topicName string, callback func(T)) SubscriptionID {
+	typ := messageType[T]()
+	return bus.subscribe(Topic{name: topicName, typ: typ}, func(msg any) {
+		callback(msg.(T))
+	}, false)
+}
+
+// messageType is the topic type for messages of type T. Pointers aren't
+// allowed, as with CreateSubscription. An interface type subscribes to every
+// message that implements it, so Subscribe[any] gets messages of any type.
+func messageType[T any]() reflect.Type {
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() == reflect.Pointer {
+		panic(fmt.Sprintf("message type cannot be a pointer, got %v", typ))
+	}
+	return typ
+}
+
+// Once subscribes to the next message on the topic only
+func Once[T any](bus *EventBus, topicName string, callback func(T)) SubscriptionID {
+	typ := messageType[T]()
+	return bus.subscribe(Topic{name: topicName, typ: typ}, func(msg any) {
+		callback(msg.(T))
+	}, true)
+}
+
+// lock also sets up a bus that wasn't made with NewEventBus. That part isn't
+// safe to race, a bus shared between goroutines has to come from NewEventBus.
+func (bus *EventBus) lock() {
+	if bus.mu == nil {
+		*bus = NewEventBus()
+	}
+	bus.mu.Lock()
+}
+
+func (bus *EventBus) unlock() {
+	bus.mu.Unlock()
+}
+
+func (bus *EventBus) subscribe(topic Topic, function func(any), once bool) SubscriptionID {
+	bus.lock()
+	defer bus.unlock()
+
+	bus.nextID++
+	sub := &Subscription{
+		function: function,
+		id:       bus.nextID,
 		active:   true,
+		once:     once,
+	}
+	bus.topics[sub.id] = topic
+	bus.subscriptions[topic] = sortByPriority(append(bus.subscriptions[topic], sub))
+	if topic.isWild() {
+		bus.wildcards[topic] = true
 	}
-	bus.subscriptions[topic] = append(bus.subscriptions[topic], sub)
 
-	return id
+	return sub.id
 }
 
-func (bus *EventBus) RemoveSubscription(topicName string, id int) {
-	for topic, subs := range bus.subscriptions {
-		if topic.name == topicName {
-			if len(subs) > id {
-				newSubs := append(subs[:id], subs[id+1:]...)
-				bus.subscriptions[topic] = newSubs
-				break
-			}
+// sortByPriority returns the subscriptions highest priority first, keeping
+// the order they were made in otherwise. It sorts a copy so a Publish that is
+// part way through the old slice isn't disturbed.
+func sortByPriority(subs []*Subscription) []*Subscription {
+	sorted := append([]*Subscription(nil), subs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].priority != sorted[j].priority {
+			return sorted[i].priority > sorted[j].priority
+		}
+		return sorted[i].id < sorted[j].id
+	})
+	return sorted
+}
+
+// SetPriority changes the order subscribers get messages in, higher
+// priorities first. Subscriptions start with a priority of zero and ties go
+// to whichever subscribed first.
+func (bus *EventBus) SetPriority(id SubscriptionID, priority int) {
+	bus.lock()
+	defer bus.unlock()
+	if sub := bus.subscription(id); sub != nil {
+		sub.priority = priority
+		topic := bus.topics[id]
+		bus.subscriptions[topic] = sortByPriority(bus.subscriptions[topic])
+	}
+}
+
+// StopPropagation keeps the message currently being delivered from reaching
+// any more subscribers, e.g. so a button can swallow a click before the world
+// behind it sees it. It should only be called from a subscription callback.
+func (bus *EventBus) StopPropagation() {
+	id := goroutineID()
+	bus.lock()
+	if stack := bus.deliveries[id]; len(stack) > 0 {
+		stack[len(stack)-1].stopped = true
+	}
+	bus.unlock()
+}
+
+// the rest of the lower case methods expect bus.mu to be held
+
+func (bus *EventBus) subscription(id SubscriptionID) *Subscription {
+	for _, sub := range bus.subscriptions[bus.topics[id]] {
+		if sub.id == id {
+			return sub
+		}
+	}
+	return nil
+}
+
+// Unsubscribe cancels a subscription. It is safe to call from a callback,
+// the subscription won't receive anything else even if the current message
+// is still being delivered.
+func (bus *EventBus) Unsubscribe(id SubscriptionID) {
+	bus.lock()
+	defer bus.unlock()
+	bus.unsubscribe(id)
+}
+
+func (bus *EventBus) unsubscribe(id SubscriptionID) {
+	sub := bus.subscription(id)
+	if sub == nil {
+		return
+	}
+	sub.removed = true
+	topic := bus.topics[id]
+	delete(bus.topics, id)
+
+	if bus.publishing > 0 {
+		bus.pending = true
+	} else {
+		bus.sweep(topic)
+	}
+}
+
+// sweep drops removed subscriptions from a topic
+func (bus *EventBus) sweep(topic Topic) {
+	var subs []*Subscription
+	for _, sub := range bus.subscriptions[topic] {
+		if !sub.removed {
+			subs = append(subs, sub)
 		}
 	}
+	if len(subs) == 0 {
+		delete(bus.subscriptions, topic)
+		delete(bus.wildcards, topic)
+	} else {
+		bus.subscriptions[topic] = subs
+	}
+}
+
+// RemoveSubscription is Unsubscribe for a subscription on the named topic
+func (bus *EventBus) RemoveSubscription(topicName string, id SubscriptionID) {
+	bus.lock()
+	defer bus.unlock()
+	if bus.topics[id].name == topicName {
+		bus.unsubscribe(id)
+	}
 }
 
 func (bus *EventBus) ClearSubscriptions(topicName string) {
-	for topic, subs := range bus.subscriptions {
+	bus.lock()
+	defer bus.unlock()
+	for id, topic := range bus.topics {
 		if topic.name == topicName {
-			bus.subscriptions[topic] = subs[:0]
+			bus.unsubscribe(id)
 		}
 	}
 }
 
-func (bus *EventBus) SuppressSubscription(topicName string, id int) {
-	for topic, subs := range bus.subscriptions {
-		if topic.name == topicName {
-			if len(subs) > id {
-				subs[id].active = false
-				break
+func (bus *EventBus) SuppressSubscription(topicName string, id SubscriptionID) {
+	bus.lock()
+	defer bus.unlock()
+	if sub := bus.subscription(id); sub != nil && bus.topics[id].name == topicName {
+		sub.active = false
+	}
+}
+
+func (bus *EventBus) UnsuppressSubscription(topicName string, id SubscriptionID) {
+	bus.lock()
+	defer bus.unlock()
+	if sub := bus.subscription(id); sub != nil && bus.topics[id].name == topicName {
+		sub.active = true
+	}
+}
+
+func (bus *EventBus) hasSubscribers(topicName string, msgType any) bool {
+	bus.lock()
+	defer bus.unlock()
+	found := false
+	bus.eachMatch(Topic{name: topicName, typ: reflect.TypeOf(msgType)}, func(subs []*Subscription) bool {
+		found = true
+		return false
+	})
+	return found
+}
+
+func (t Topic) isWild() bool {
+	return t.typ.Kind() == reflect.Interface || strings.Contains(t.name, "*")
+}
+
+// matches says whether a message published on topic should reach
+// subscribers of t
+func (t Topic) matches(topic Topic) bool {
+	if t.typ != topic.typ && !(t.typ.Kind() == reflect.Interface && topic.typ.Implements(t.typ)) {
+		return false
+	}
+	if strings.Contains(t.name, "*") {
+		return matchPattern(strings.Split(t.name, "."), strings.Split(topic.name, "."))
+	}
+	return t.name == topic.name || strings.HasPrefix(topic.name, t.name+".")
+}
+
+func matchPattern(pattern []string, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	switch pattern[0] {
+	case "**":
+		for i := 1; i <= len(parts); i++ {
+			if matchPattern(pattern[1:], parts[i:]) {
+				return true
 			}
 		}
+		return false
+	case "*":
+		return matchPattern(pattern[1:], parts[1:])
+	default:
+		return pattern[0] == parts[0] && matchPattern(pattern[1:], parts[1:])
 	}
 }
 
-func (bus *EventBus) UnsuppressSubscription(topicName string, id int) {
-	for topic, subs := range bus.subscriptions {
-		if topic.name == topicName {
-			if len(subs) > id {
-				subs[id].active = true
-				break
+// eachMatch calls f with the subscriptions of every topic a message published
+// on topic reaches, until f returns false
+func (bus *EventBus) eachMatch(topic Topic, f func(subs []*Subscription) bool) {
+	// the topic itself and every topic above it. A name with a "*" in it is
+	// a pattern, left to the loop below so publishing on it literally
+	// doesn't reach its subscribers twice.
+	name := topic.name
+	for {
+		exact := Topic{name: name, typ: topic.typ}
+		if subs, ok := bus.subscriptions[exact]; ok && !bus.wildcards[exact] {
+			if !f(subs) {
+				return
 			}
 		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
 	}
-}
 
-func (bus *EventBus) Publish(topicName string, msg any) {
+	for wild := range bus.wildcards {
+		if wild.matches(topic) {
+			if !f(bus.subscriptions[wild]) {
+				return
+			}
+		}
+	}
+}
 
-	if len(bus.subscriptions) == 0 {
-		return
+// matching is every subscription a message published on topic reaches, in
+// the order they should get it
+func (bus *EventBus) matching(topic Topic) []*Subscription {
+	var matched []*Subscription
+	topics := 0
+	bus.eachMatch(topic, func(subs []*Subscription) bool {
+		matched = append(matched, subs...)
+		topics++
+		return true
+	})
+	if topics > 1 {
+		matched = sortByPriority(matched)
 	}
+	return matched
+}
+
+// DeadLetterTopic is where messages that reach no subscribers are published,
+// as a DeadLetter. Dead letters that nobody receives are dropped.
+const DeadLetterTopic = "deadletter"
+
+type DeadLetter struct {
+	Topic   string
+	Message any
+}
+
+// ErrNilMessage is returned when publishing nil, there would be no type to
+// pick the topic by
+var ErrNilMessage = errors.New("cannot publish a nil message")
+
+// TypeMismatchError is returned in strict mode when a Publisher is given a
+// message of a different type to the one it was created with
+type TypeMismatchError struct {
+	Topic    string
+	Expected reflect.Type
+	Got      reflect.Type
+}
 
+func (e *TypeMismatchError) Error() string {
+	return fmt.Sprintf("topic %s expects %v but was given %v", e.Topic, e.Expected, e.Got)
+}
+
+// Publish delivers a message to every subscriber of its topic and type, or
+// queues it, see SetQueued. A nil message has no type to pick the topic by so
+// it returns ErrNilMessage, where Publish used to panic and return nothing.
+func (bus *EventBus) Publish(topicName string, msg any) error {
 	msgType := reflect.TypeOf(msg)
 
 	if msgType == nil {
-		panic("msg cannot be nil")
+		return ErrNilMessage
 	}
 
+	bus.publish(Topic{name: topicName, typ: msgType}, msg)
+	return nil
+}
+
+// SetStrict makes Publishers return a TypeMismatchError rather than publish
+// a message of the wrong type
+func (bus *EventBus) SetStrict(strict bool) {
+	bus.lock()
+	bus.strict = strict
+	bus.unlock()
+}
+
+func (bus *EventBus) IsStrict() bool {
+	bus.lock()
+	defer bus.unlock()
+	return bus.strict
+}
+
+type TopicInfo struct {
+	Name        string
+	Type        reflect.Type
+	Subscribers int
+}
+
+// Topics lists every topic with subscribers, sorted by name and then type
+func (bus *EventBus) Topics() []TopicInfo {
+	bus.lock()
+	defer bus.unlock()
+
+	var topics []TopicInfo
 	for topic, subs := range bus.subscriptions {
-		if topicName == topic.name && topic.typ == msgType {
-			for _, sub := range subs {
-				if sub.active {
-					sub.function(msg)
-				}
+		count := 0
+		for _, sub := range subs {
+			if !sub.removed {
+				count++
 			}
-			return
+		}
+		if count > 0 {
+			topics = append(topics, TopicInfo{Name: topic.name, Type: topic.typ, Subscribers: count})
+		}
+	}
+	sort.Slice(topics, func(i, j int) bool {
+		if topics[i].Name != topics[j].Name {
+			return topics[i].Name < topics[j].Name
+		}
+		return topics[i].Type.String() < topics[j].Type.String()
+	})
+	return topics
+}
+
+// SetQueued switches between delivering messages as soon as they are
+// published and queueing them until Dispatch is called. A game dispatches
+// its bus at the end of each Update. Even when it isn't queued, a game's bus
+// queues messages published from other goroutines.
+func (bus *EventBus) SetQueued(queued bool) {
+	bus.lock()
+	bus.queued = queued
+	bus.unlock()
+}
+
+func (bus *EventBus) IsQueued() bool {
+	bus.lock()
+	defer bus.unlock()
+	return bus.queued
+}
+
+// Enqueue queues a message for the next Dispatch whether or not the bus is
+// in queued mode. This is how other goroutines should publish.
+func (bus *EventBus) Enqueue(topicName string, msg any) error {
+	msgType := reflect.TypeOf(msg)
+	if msgType == nil {
+		return ErrNilMessage
+	}
+	bus.lock()
+	bus.queue = append(bus.queue, queuedMessage{topic: Topic{name: topicName, typ: msgType}, msg: msg})
+	bus.unlock()
+	return nil
+}
+
+// Dispatch delivers the queued messages in the order they were published.
+// Anything queued by the callbacks waits for the next Dispatch.
+func (bus *EventBus) Dispatch() {
+	bus.lock()
+	queue := bus.queue
+	bus.queue = nil
+	bus.unlock()
+
+	for _, queued := range queue {
+		bus.deliver(queued.topic, queued.msg)
+	}
+}
+
+func (bus *EventBus) publish(topic Topic, msg any) {
+	bus.lock()
+	if bus.queued || (bus.owner != 0 && goroutineID() != bus.owner) {
+		bus.queue = append(bus.queue, queuedMessage{topic: topic, msg: msg})
+		bus.unlock()
+		return
+	}
+	bus.unlock()
+
+	bus.deliver(topic, msg)
+}
+
+func (bus *EventBus) deliver(topic Topic, msg any) {
+	gid := goroutineID()
+	bus.lock()
+	subs := bus.matching(topic)
+	if len(subs) == 0 {
+		bus.unlock()
+		if topic.name != DeadLetterTopic {
+			bus.deliver(Topic{name: DeadLetterTopic, typ: reflect.TypeFor[DeadLetter]()}, DeadLetter{Topic: topic.name, Message: msg})
+		}
+		return
+	}
+	bus.publishing++
+	d := &delivery{}
+	bus.deliveries[gid] = append(bus.deliveries[gid], d)
+	bus.unlock()
+
+	defer func() {
+		bus.lock()
+		if stack := bus.deliveries[gid]; len(stack) > 1 {
+			bus.deliveries[gid] = stack[:len(stack)-1]
+		} else {
+			delete(bus.deliveries, gid)
+		}
+		bus.endPublish()
+		bus.unlock()
+	}()
+	// subs is a snapshot, subscriptions made by the callbacks only see later
+	// messages
+	for _, sub := range subs {
+		bus.lock()
+		ok := sub.active && !sub.removed
+		if ok && sub.once {
+			bus.unsubscribe(sub.id)
+		}
+		bus.unlock()
+
+		if ok {
+			sub.function(msg)
+
+			bus.lock()
+			stop := d.stopped
+			bus.unlock()
+			if stop {
+				break
+			}
+		}
+	}
+}
+
+func (bus *EventBus) endPublish() {
+	bus.publishing--
+	if bus.publishing == 0 && bus.pending {
+		bus.pending = false
+		for topic := range bus.subscriptions {
+			bus.sweep(topic)
 		}
 	}
-	fmt.Printf("Topic %s not found for type %s\n", topicName, msgType)
 }
 
 type Publisher struct {
@@ -132,8 +598,16 @@ type Publisher struct {
 	topic Topic
 }
 
-func (p *Publisher) Publish(msg any) {
-	p.bus.Publish(p.topic.name, msg)
+func (p *Publisher) Publish(msg any) error {
+	msgType := reflect.TypeOf(msg)
+	if msgType == nil {
+		return ErrNilMessage
+	}
+	if msgType != p.topic.typ && p.bus.IsStrict() {
+		return &TypeMismatchError{Topic: p.topic.name, Expected: p.topic.typ, Got: msgType}
+	}
+	p.bus.publish(Topic{name: p.topic.name, typ: msgType}, msg)
+	return nil
 }
 
 func (bus *EventBus) CreatePublisher(topicName string, msgType any) *Publisher {
@@ -149,3 +623,45 @@ func (bus *EventBus) CreatePublisher(topicName string, msgType any) *Publisher {
 		topic: Topic{name: topicName, typ: typ},
 	}
 }
+
+// TypedPublisher is a Publisher that only accepts messages of type T
+type TypedPublisher[T any] struct {
+	bus   *EventBus
+	topic Topic
+}
+
+func NewPublisher[T any](bus *EventBus, topicName string) *TypedPublisher[T] {
+	if reflect.TypeFor[T]().Kind() == reflect.Interface {
+		panic("NewPublisher needs a concrete message type")
+	}
+	return &TypedPublisher[T]{
+		bus:   bus,
+		topic: Topic{name: topicName, typ: messageType[T]()},
+	}
+}
+
+func (p *TypedPublisher[T]) Publish(msg T) {
+	p.bus.publish(p.topic, msg)
+}
+
+// setOwner makes the goroutine with the given id the only one that delivers
+// straight away, see owner
+func (bus *EventBus) setOwner(id int64) {
+	bus.lock()
+	bus.owner = id
+	bus.unlock()
+}
+
+// goroutineID is the id of the calling goroutine, parsed from the first line
+// of its stack trace, "goroutine 1 [running]:". Go doesn't offer it any other
+// way.
+func goroutineID() int64 {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	fields := strings.Fields(string(buf[:n]))
+	if len(fields) < 2 {
+		return 0
+	}
+	id, _ := strconv.ParseInt(fields[1], 10, 64)
+	return id
+}
@@ -0,0 +1,523 @@
+package raychip
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type testMessage struct {
+	Value int
+}
+
+type otherMessage struct {
+	Value int
+}
+
+func TestSubscribe(t *testing.T) {
+	bus := NewEventBus()
+	var typed, reflected []int
+	Subscribe(&bus, "test", func(msg testMessage) { typed = append(typed, msg.Value) })
+	bus.CreateSubscription("test", testMessage{}, func(msg testMessage) { reflected = append(reflected, msg.Value) })
+
+	bus.Publish("test", testMessage{Value: 1})
+	// same topic name, different type, different topic
+	bus.Publish("test", otherMessage{Value: 2})
+	bus.Publish("other", testMessage{Value: 3})
+
+	if fmt.Sprint(typed) != "[1]" || fmt.Sprint(reflected) != "[1]" {
+		t.Errorf("Subscribe got %v, CreateSubscription got %v, want [1] for both", typed, reflected)
+	}
+}
+
+func TestSubscribeRejectsPointers(t *testing.T) {
+	tests := []struct {
+		name      string
+		subscribe func(bus *EventBus)
+	}{
+		{"pointer", func(bus *EventBus) { Subscribe(bus, "test", func(*testMessage) {}) }},
+		{"pointer publisher", func(bus *EventBus) { NewPublisher[*testMessage](bus, "test") }},
+		{"interface publisher", func(bus *EventBus) { NewPublisher[any](bus, "test") }},
+	}
+	for _, test := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: didn't panic", test.name)
+				}
+			}()
+			bus := NewEventBus()
+			test.subscribe(&bus)
+		}()
+	}
+}
+
+func TestTypedPublisher(t *testing.T) {
+	bus := NewEventBus()
+	// publishing before anyone subscribes is fine
+	publisher := NewPublisher[testMessage](&bus, "test")
+	publisher.Publish(testMessage{Value: 1})
+
+	var got []int
+	Subscribe(&bus, "test", func(msg testMessage) { got = append(got, msg.Value) })
+	bus.CreateSubscription("test", testMessage{}, func(msg testMessage) { got = append(got, msg.Value*10) })
+	Subscribe(&bus, "test", func(msg otherMessage) { t.Errorf("other message type got %v", msg) })
+	publisher.Publish(testMessage{Value: 2})
+
+	if fmt.Sprint(got) != "[2 20]" {
+		t.Errorf("subscribers got %v, want [2 20]", got)
+	}
+}
+
+func TestUnsubscribeDuringPublish(t *testing.T) {
+	bus := NewEventBus()
+	var got []string
+	var second SubscriptionID
+	first := Subscribe(&bus, "test", func(msg testMessage) {
+		got = append(got, "first")
+		bus.Unsubscribe(second)
+	})
+	second = Subscribe(&bus, "test", func(msg testMessage) { got = append(got, "second") })
+	third := Subscribe(&bus, "test", func(msg testMessage) { got = append(got, "third") })
+	if first == 0 || first == second || second == third {
+		t.Fatalf("subscription ids %v, %v, %v aren't unique", first, second, third)
+	}
+
+	bus.Publish("test", testMessage{})
+	bus.Publish("test", testMessage{})
+
+	if fmt.Sprint(got) != "[first third first third]" {
+		t.Errorf("delivered to %v", got)
+	}
+
+	// ids aren't reused once the old ones are swept away
+	if fourth := Subscribe(&bus, "test", func(testMessage) {}); fourth == second {
+		t.Errorf("id %v reused", fourth)
+	}
+}
+
+func TestOnce(t *testing.T) {
+	bus := NewEventBus()
+	var got []int
+	Once(&bus, "test", func(msg testMessage) {
+		got = append(got, msg.Value)
+		// publishing from inside the callback doesn't deliver to it again
+		bus.Publish("test", testMessage{Value: msg.Value + 1})
+	})
+
+	bus.Publish("test", testMessage{Value: 1})
+	bus.Publish("test", testMessage{Value: 10})
+
+	if fmt.Sprint(got) != "[1]" {
+		t.Errorf("Once callback got %v, want [1]", got)
+	}
+}
+
+func TestPriority(t *testing.T) {
+	bus := NewEventBus()
+	var got []string
+	low := Subscribe(&bus, "test", func(testMessage) { got = append(got, "low") })
+	Subscribe(&bus, "test", func(testMessage) { got = append(got, "first") })
+	high := Subscribe(&bus, "test", func(testMessage) { got = append(got, "high") })
+	Subscribe(&bus, "test", func(testMessage) { got = append(got, "second") })
+	bus.SetPriority(low, -1)
+	bus.SetPriority(high, 5)
+
+	bus.Publish("test", testMessage{})
+
+	// ties keep the order they subscribed in
+	if fmt.Sprint(got) != "[high first second low]" {
+		t.Errorf("delivered in order %v", got)
+	}
+}
+
+func TestStopPropagation(t *testing.T) {
+	bus := NewEventBus()
+	var got []string
+	button := Subscribe(&bus, "click", func(msg testMessage) {
+		got = append(got, fmt.Sprint("button ", msg.Value))
+		if msg.Value == 1 {
+			// a message published while delivering isn't stopped by the
+			// outer StopPropagation, nor does its own stop leak out
+			bus.Publish("other", testMessage{})
+			bus.StopPropagation()
+		}
+	})
+	bus.SetPriority(button, 1)
+	Subscribe(&bus, "click", func(msg testMessage) { got = append(got, fmt.Sprint("world ", msg.Value)) })
+	Subscribe(&bus, "other", func(testMessage) {
+		got = append(got, "other")
+		bus.StopPropagation()
+	})
+	Subscribe(&bus, "other", func(testMessage) { got = append(got, "stopped") })
+
+	bus.Publish("click", testMessage{Value: 1})
+	bus.Publish("click", testMessage{Value: 2})
+
+	if fmt.Sprint(got) != "[button 1 other button 2 world 2]" {
+		t.Errorf("delivered %v", got)
+	}
+}
+
+func TestQueued(t *testing.T) {
+	bus := NewEventBus()
+	var got []int
+	Subscribe(&bus, "test", func(msg testMessage) {
+		got = append(got, msg.Value)
+		if msg.Value == 1 {
+			bus.Publish("test", testMessage{Value: 3})
+		}
+	})
+	bus.SetQueued(true)
+
+	bus.Publish("test", testMessage{Value: 1})
+	bus.Publish("test", testMessage{Value: 2})
+	if len(got) != 0 {
+		t.Fatalf("queued bus delivered %v straight away", got)
+	}
+
+	// messages published while dispatching wait for the next Dispatch
+	bus.Dispatch()
+	if fmt.Sprint(got) != "[1 2]" {
+		t.Errorf("first Dispatch delivered %v, want [1 2]", got)
+	}
+	bus.Dispatch()
+	if fmt.Sprint(got) != "[1 2 3]" {
+		t.Errorf("second Dispatch delivered %v, want [1 2 3]", got)
+	}
+
+	// Enqueue queues even when the bus isn't in queued mode
+	bus.SetQueued(false)
+	bus.Enqueue("test", testMessage{Value: 4})
+	bus.Publish("test", testMessage{Value: 5})
+	bus.Dispatch()
+	if fmt.Sprint(got) != "[1 2 3 5 4]" {
+		t.Errorf("delivered %v, want [1 2 3 5 4]", got)
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern, topic string
+		want           bool
+	}{
+		{"input.*", "input.mouse", true},
+		{"input.*", "input.gamepad.connection", false},
+		{"input.*", "input", false},
+		{"*.mouse", "input.mouse", true},
+		{"*", "input", true},
+		{"*", "input.mouse", false},
+		{"input.**", "input.mouse", true},
+		{"input.**", "input.gamepad.connection", true},
+		// "**" matches one or more parts, never none
+		{"input.**", "input", false},
+		{"**", "input", true},
+		{"**.connection", "input.gamepad.connection", true},
+		{"**.connection", "connection", false},
+		{"input.**.connection", "input.gamepad.connection", true},
+		{"input.**.connection", "input.a.b.connection", true},
+		{"input.*.connection", "input.a.b.connection", false},
+		{"collision.*.enemy", "collision.player.enemy", true},
+		{"collision.*.enemy", "collision.player.wall", false},
+	}
+	for _, test := range tests {
+		got := matchPattern(strings.Split(test.pattern, "."), strings.Split(test.topic, "."))
+		if got != test.want {
+			t.Errorf("matchPattern(%q, %q) = %v, want %v", test.pattern, test.topic, got, test.want)
+		}
+	}
+}
+
+func TestTopicHierarchy(t *testing.T) {
+	tests := []struct {
+		subscribed string
+		published  string
+		want       bool
+	}{
+		{"collision", "collision", true},
+		{"collision", "collision.player", true},
+		{"collision", "collision.player.enemy", true},
+		{"collision.player", "collision.player.enemy", true},
+		{"collision.player.enemy", "collision.player", false},
+		// prefixes are whole parts, not any string prefix
+		{"collision", "collisions", false},
+		{"collision.player", "collision.playerone", false},
+		// patterns aren't extended below what they match
+		{"collision.*", "collision.player", true},
+		{"collision.*", "collision.player.enemy", false},
+		{"collision.**", "collision.player.enemy", true},
+		{"collision.**", "collision", false},
+	}
+	for _, test := range tests {
+		bus := NewEventBus()
+		got := 0
+		Subscribe(&bus, test.subscribed, func(testMessage) { got++ })
+		bus.Publish(test.published, testMessage{})
+		if (got == 1) != test.want || got > 1 {
+			t.Errorf("subscribed to %q, publishing on %q delivered %d times", test.subscribed, test.published, got)
+		}
+	}
+}
+
+func TestPublishOnPatternName(t *testing.T) {
+	bus := NewEventBus()
+	got := 0
+	Subscribe(&bus, "input.*", func(testMessage) { got++ })
+	bus.Publish("input.*", testMessage{})
+	if got != 1 {
+		t.Errorf("publishing on the pattern itself delivered %d times, want 1", got)
+	}
+}
+
+type valued interface {
+	value() int
+}
+
+func (m testMessage) value() int  { return m.Value }
+func (m otherMessage) value() int { return m.Value * 10 }
+
+func TestInterfaceSubscriptions(t *testing.T) {
+	bus := NewEventBus()
+	var all, values []int
+	var typed []string
+	Subscribe(&bus, "test", func(msg any) { typed = append(typed, fmt.Sprintf("%T", msg)) })
+	Subscribe(&bus, "test", func(msg valued) { values = append(values, msg.value()) })
+	Subscribe(&bus, "**", func(msg valued) { all = append(all, msg.value()) })
+
+	bus.Publish("test", testMessage{Value: 1})
+	bus.Publish("test", otherMessage{Value: 2})
+	bus.Publish("test", "not valued")
+	bus.Publish("test.child", testMessage{Value: 3})
+
+	// interface subscriptions get the topics below theirs like any other
+	if fmt.Sprint(typed) != "[raychip.testMessage raychip.otherMessage string raychip.testMessage]" {
+		t.Errorf("any subscriber got %v", typed)
+	}
+	if fmt.Sprint(values) != "[1 20 3]" {
+		t.Errorf("interface subscriber got %v, want [1 20 3]", values)
+	}
+	if fmt.Sprint(all) != "[1 20 3]" {
+		t.Errorf("interface subscriber to ** got %v, want [1 20 3]", all)
+	}
+}
+
+func TestPriorityAcrossTopics(t *testing.T) {
+	bus := NewEventBus()
+	var got []string
+	Subscribe(&bus, "input", func(testMessage) { got = append(got, "parent") })
+	child := Subscribe(&bus, "input.mouse", func(testMessage) { got = append(got, "child") })
+	wild := Subscribe(&bus, "*.mouse", func(testMessage) {
+		got = append(got, "wild")
+		bus.StopPropagation()
+	})
+	bus.SetPriority(child, 1)
+	bus.SetPriority(wild, 0)
+
+	bus.Publish("input.mouse", testMessage{})
+
+	// the child goes first on priority, then the rest in subscription order
+	// until the pattern stops it
+	if fmt.Sprint(got) != "[child parent wild]" {
+		t.Errorf("delivered in order %v", got)
+	}
+}
+
+type ping struct {
+	N int
+}
+
+type pong struct {
+	N int
+}
+
+// run with -race, this is mostly checking the bus's locking
+func TestBusConcurrentPublish(t *testing.T) {
+	bus := NewEventBus()
+	var got, stopped, after atomic.Int64
+	Subscribe(&bus, "ping", func(ping) { got.Add(1) })
+	// the first subscriber to "pong" swallows every message
+	first := Subscribe(&bus, "pong", func(pong) {
+		stopped.Add(1)
+		bus.StopPropagation()
+	})
+	bus.SetPriority(first, 1)
+	Subscribe(&bus, "pong", func(pong) { after.Add(1) })
+
+	const goroutines, messages = 8, 200
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < messages; i++ {
+				switch i % 3 {
+				case 0:
+					bus.Publish("ping", ping{N: i})
+				case 1:
+					bus.Enqueue("ping", ping{N: i})
+				case 2:
+					bus.Publish("pong", pong{N: i})
+				}
+			}
+		}()
+	}
+	// subscribers coming and going, and a dispatcher, alongside the publishers
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < messages; i++ {
+			id := Subscribe(&bus, "ping", func(ping) {})
+			Once(&bus, "ping", func(ping) {})
+			bus.Unsubscribe(id)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < messages; i++ {
+			bus.Dispatch()
+		}
+	}()
+	wg.Wait()
+	bus.Dispatch()
+
+	var pings, pongs int64
+	for i := 0; i < messages; i++ {
+		if i%3 == 2 {
+			pongs++
+		} else {
+			pings++
+		}
+	}
+	if n := got.Load(); n != pings*goroutines {
+		t.Errorf("got %d pings, want %d", n, pings*goroutines)
+	}
+	if n := stopped.Load(); n != pongs*goroutines {
+		t.Errorf("first subscriber got %d pongs, want %d", n, pongs*goroutines)
+	}
+	if n := after.Load(); n != 0 {
+		t.Errorf("%d pongs got past StopPropagation", n)
+	}
+}
+
+func TestBusOwner(t *testing.T) {
+	bus := NewEventBus()
+	bus.setOwner(goroutineID())
+	var got []int
+	Subscribe(&bus, "ping", func(msg ping) { got = append(got, msg.N) })
+
+	// the owner's messages are delivered straight away
+	bus.Publish("ping", ping{N: 1})
+	if fmt.Sprint(got) != "[1]" {
+		t.Fatalf("owner's publish delivered %v", got)
+	}
+
+	// everyone else's wait for the owner to dispatch them
+	done := make(chan struct{})
+	go func() {
+		bus.Publish("ping", ping{N: 2})
+		close(done)
+	}()
+	<-done
+	if fmt.Sprint(got) != "[1]" {
+		t.Fatalf("other goroutine's publish delivered straight away, got %v", got)
+	}
+	bus.Dispatch()
+	if fmt.Sprint(got) != "[1 2]" {
+		t.Errorf("got %v after Dispatch, want [1 2]", got)
+	}
+}
+
+func TestDeadLetters(t *testing.T) {
+	bus := NewEventBus()
+	var dead []DeadLetter
+	// an empty bus still routes to dead letters once someone listens
+	Subscribe(&bus, DeadLetterTopic, func(letter DeadLetter) { dead = append(dead, letter) })
+
+	bus.Publish("nobody", testMessage{Value: 1})
+	Subscribe(&bus, "somebody", func(testMessage) {})
+	bus.Publish("somebody", testMessage{Value: 2})
+	// right name, wrong type
+	bus.Publish("somebody", otherMessage{Value: 3})
+
+	if len(dead) != 2 {
+		t.Fatalf("got %d dead letters, want 2: %+v", len(dead), dead)
+	}
+	if dead[0].Topic != "nobody" || dead[0].Message != (testMessage{Value: 1}) {
+		t.Errorf("first dead letter is %+v", dead[0])
+	}
+	if dead[1].Topic != "somebody" || dead[1].Message != (otherMessage{Value: 3}) {
+		t.Errorf("second dead letter is %+v", dead[1])
+	}
+
+	// dead letters nobody receives are just dropped
+	quiet := NewEventBus()
+	if err := quiet.Publish("nobody", testMessage{}); err != nil {
+		t.Errorf("publishing to nobody returned %v", err)
+	}
+}
+
+func TestNilMessages(t *testing.T) {
+	bus := NewEventBus()
+	Subscribe(&bus, "test", func(testMessage) { t.Error("nil message delivered") })
+	if err := bus.Publish("test", nil); !errors.Is(err, ErrNilMessage) {
+		t.Errorf("Publish(nil) returned %v", err)
+	}
+	if err := bus.Enqueue("test", nil); !errors.Is(err, ErrNilMessage) {
+		t.Errorf("Enqueue(nil) returned %v", err)
+	}
+	if err := bus.CreatePublisher("test", testMessage{}).Publish(nil); !errors.Is(err, ErrNilMessage) {
+		t.Errorf("Publisher.Publish(nil) returned %v", err)
+	}
+	bus.Dispatch()
+}
+
+func TestStrictPublisher(t *testing.T) {
+	bus := NewEventBus()
+	var got []any
+	Subscribe(&bus, "test", func(msg testMessage) { got = append(got, msg) })
+	Subscribe(&bus, "test", func(msg otherMessage) { got = append(got, msg) })
+	publisher := bus.CreatePublisher("test", testMessage{})
+
+	// loose buses publish whatever they are given on the publisher's topic
+	if err := publisher.Publish(otherMessage{Value: 1}); err != nil {
+		t.Errorf("loose publish returned %v", err)
+	}
+
+	bus.SetStrict(true)
+	err := publisher.Publish(otherMessage{Value: 2})
+	var mismatch *TypeMismatchError
+	if !errors.As(err, &mismatch) || mismatch.Topic != "test" ||
+		mismatch.Expected != reflect.TypeFor[testMessage]() || mismatch.Got != reflect.TypeFor[otherMessage]() {
+		t.Errorf("strict publish of the wrong type returned %v", err)
+	}
+	if err := publisher.Publish(testMessage{Value: 3}); err != nil {
+		t.Errorf("strict publish of the right type returned %v", err)
+	}
+
+	if fmt.Sprint(got) != "[{1} {3}]" {
+		t.Errorf("delivered %v, want [{1} {3}]", got)
+	}
+}
+
+func TestTopics(t *testing.T) {
+	bus := NewEventBus()
+	Subscribe(&bus, "b", func(testMessage) {})
+	Subscribe(&bus, "b", func(testMessage) {})
+	Subscribe(&bus, "a", func(testMessage) {})
+	Subscribe(&bus, "b", func(otherMessage) {})
+	gone := Subscribe(&bus, "c", func(testMessage) {})
+	bus.Unsubscribe(gone)
+
+	var got []string
+	for _, topic := range bus.Topics() {
+		got = append(got, fmt.Sprintf("%s %v %d", topic.Name, topic.Type, topic.Subscribers))
+	}
+	want := "[a raychip.testMessage 1 b raychip.otherMessage 1 b raychip.testMessage 2]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("Topics() = %v, want %v", got, want)
+	}
+}
@@ -30,46 +30,23 @@ func main() {
 
 	const inc float64 = 10
 
+	game.InputMap().Bind("move_x", KeyBinding(rl.KeyA).Scaled(-1), KeyBinding(rl.KeyD))
+	game.InputMap().Bind("move_y", KeyBinding(rl.KeyW).Scaled(-1), KeyBinding(rl.KeyS))
+
 	game.SetUpdateCallback(func(g *Game) {
 
 		// move with w,a,s,d if selected
-		newBallPos := ball.Position()
-		newBoxPos := box.Position()
+		dx := g.ActionValue("move_x") * inc
+		dy := g.ActionValue("move_y") * inc
 
-		if rl.IsKeyDown(rl.KeyW) {
-			if ballSelect == 1 {
-				newBallPos.Y -= inc
-			}
-			if boxSelect == 1 {
-				newBoxPos.Y -= inc
-			}
-		}
-		if rl.IsKeyDown(rl.KeyS) {
-			if ballSelect == 1 {
-				newBallPos.Y += inc
-			}
-			if boxSelect == 1 {
-				newBoxPos.Y += inc
-			}
-		}
-		if rl.IsKeyDown(rl.KeyD) {
-			if ballSelect == 1 {
-				newBallPos.X += inc
-			}
-			if boxSelect == 1 {
-				newBoxPos.X += inc
-			}
+		if ballSelect == 1 {
+			newBallPos := ball.Position()
+			ball.SetPosition(newBallPos.X+dx, newBallPos.Y+dy)
 		}
-		if rl.IsKeyDown(rl.KeyA) {
-			if ballSelect == 1 {
-				newBallPos.X -= inc
-			}
-			if boxSelect == 1 {
-				newBoxPos.X -= inc
-			}
+		if boxSelect == 1 {
+			newBoxPos := box.Position()
+			box.SetPosition(newBoxPos.X+dx, newBoxPos.Y+dy)
 		}
-		ball.SetPosition(newBallPos.X, newBallPos.Y)
-		box.SetPosition(newBoxPos.X, newBoxPos.Y)
 	})
 
 	game.Run()
@@ -21,10 +21,25 @@ func main() {
 	// Create a permiter wall (invisible)
 	game.AddPerimiterWall(1, rl.NewColor(0, 0, 0, 0))
 
-	// Cursor texture, hide default cursor
-	cursorTexture := rl.LoadTexture("./assets/cursors/Tiles/tile_0026.png")
+	// Cursor from the packed tile sheet, hide default cursor
+	cursorTiles := rl.LoadTexture("./assets/cursors/Tilemap/tilemap_packed.png")
+	cursorGrid, err := LoadTilesheet("./assets/cursors/Tilesheet.txt")
+	if err != nil {
+		panic(err)
+	}
+	cursorSheet, err := NewGridSpriteSheet(cursorTiles, cursorGrid)
+	if err != nil {
+		panic(err)
+	}
 	rl.HideCursor()
 
+	// the cursor goes in the UI layer so it's drawn on top of everything
+	cursor := NewSheetSprite(0, 0, cursorSheet, 26)
+	cursor.SetOrigin(NewVector2(0, 0))
+	cursor.SetScale(2, 2)
+	cursor.SetLayer(LayerUI)
+	game.AddEntity(&cursor)
+
 	// Texture for the circles
 	ballTexture := rl.LoadTexture("./assets/planets/Terran.png")
 
@@ -39,6 +54,7 @@ func main() {
 		mousePos = Vector2FromRaylib(rl.GetMousePosition())
 		mouseVel = Vector2FromRaylib(rl.Vector2Scale(rl.GetMouseDelta(), 50.0))
 		numEntities = game.EntitiesCount()
+		cursor.SetPosition(mousePos.X, mousePos.Y)
 
 		// lift click adds a ball with the velocity of the mouse
 		if rl.IsMouseButtonReleased(rl.MouseButtonLeft) {
@@ -68,8 +84,7 @@ func main() {
 
 	// Custom game draw function
 	game.SetDrawCallback(func(game *Game) {
-		rl.DrawTextureEx(cursorTexture, mousePos.ToRaylib(), 0.0, 2.0, rl.White)
-		rl.DrawText(fmt.Sprintf("Entities: %d\n", numEntities-4), 10, 10, 20, rl.Black)
+		rl.DrawText(fmt.Sprintf("Entities: %d\n", numEntities-5), 10, 10, 20, rl.Black)
 	})
 
 	// Run the main game loop
@@ -0,0 +1,205 @@
+package raychip
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// ImageRenderer draws into an image.RGBA in pure Go, for golden image tests
+// on machines without a GPU. It fills pixels whose centres are inside each
+// shape, without anti-aliasing. Textures are drawn from images added with
+// AddTexture, or as tinted rectangles otherwise, and text isn't drawn.
+type ImageRenderer struct {
+	img      *image.RGBA
+	camera   *Camera
+	textures map[uint32]image.Image
+}
+
+func NewImageRenderer(width int, height int) *ImageRenderer {
+	return &ImageRenderer{
+		img:      image.NewRGBA(image.Rect(0, 0, width, height)),
+		textures: make(map[uint32]image.Image),
+	}
+}
+
+func (r *ImageRenderer) Image() *image.RGBA {
+	return r.img
+}
+
+// AddTexture gives the pixels to draw for a texture, matched by its ID
+func (r *ImageRenderer) AddTexture(texture rl.Texture2D, img image.Image) {
+	r.textures[texture.ID] = img
+}
+
+func (r *ImageRenderer) BeginFrame(background rl.Color) {
+	bounds := r.img.Bounds()
+	// image.RGBA is premultiplied
+	c := color.RGBAModel.Convert(color.NRGBA{R: background.R, G: background.G, B: background.B, A: background.A}).(color.RGBA)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r.img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+func (r *ImageRenderer) EndFrame() {}
+
+func (r *ImageRenderer) BeginCamera(camera Camera) {
+	r.camera = &camera
+}
+
+func (r *ImageRenderer) EndCamera() {
+	r.camera = nil
+}
+
+func (r *ImageRenderer) toScreen(points []Vector2) []Vector2 {
+	if r.camera == nil {
+		return points
+	}
+	out := make([]Vector2, len(points))
+	for i, p := range points {
+		out[i] = r.camera.WorldToScreen(p)
+	}
+	return out
+}
+
+func (r *ImageRenderer) toWorld(p Vector2) Vector2 {
+	if r.camera == nil {
+		return p
+	}
+	return r.camera.ScreenToWorld(p)
+}
+
+func (r *ImageRenderer) DrawRectangle(rect rl.Rectangle, origin Vector2, rotation float64, c rl.Color) {
+	r.DrawPolygon(rectangleCorners(rect, origin, rotation), c)
+}
+
+func (r *ImageRenderer) DrawCircle(center Vector2, radius float64, c rl.Color) {
+	if r.camera != nil {
+		center = r.camera.WorldToScreen(center)
+		radius *= r.camera.Zoom()
+	}
+	min := NewVector2(center.X-radius, center.Y-radius)
+	max := NewVector2(center.X+radius, center.Y+radius)
+	r.fill(min, max, func(p Vector2) bool {
+		dx, dy := p.X-center.X, p.Y-center.Y
+		return dx*dx+dy*dy <= radius*radius
+	}, func(Vector2) rl.Color {
+		return c
+	})
+}
+
+func (r *ImageRenderer) DrawLine(start Vector2, end Vector2, thickness float64, c rl.Color) {
+	length := math.Hypot(end.X-start.X, end.Y-start.Y)
+	if length == 0 {
+		return
+	}
+	// a line is a rectangle along it
+	nx := -(end.Y - start.Y) / length * thickness / 2
+	ny := (end.X - start.X) / length * thickness / 2
+	r.DrawPolygon([]Vector2{
+		NewVector2(start.X+nx, start.Y+ny),
+		NewVector2(end.X+nx, end.Y+ny),
+		NewVector2(end.X-nx, end.Y-ny),
+		NewVector2(start.X-nx, start.Y-ny),
+	}, c)
+}
+
+func (r *ImageRenderer) DrawPolygon(points []Vector2, c rl.Color) {
+	if len(points) < 3 {
+		return
+	}
+	screen := r.toScreen(points)
+	min, max := bounds(screen)
+	r.fill(min, max, func(p Vector2) bool {
+		return pointInPolygon(p, screen)
+	}, func(Vector2) rl.Color {
+		return c
+	})
+}
+
+func (r *ImageRenderer) DrawTexture(texture rl.Texture2D, src rl.Rectangle, dest rl.Rectangle, origin Vector2, rotation float64, tint rl.Color) {
+	img, ok := r.textures[texture.ID]
+	if !ok {
+		r.DrawRectangle(dest, origin, rotation, tint)
+		return
+	}
+
+	screen := r.toScreen(rectangleCorners(dest, origin, rotation))
+	min, max := bounds(screen)
+	sin, cos := math.Sincos(-rotation)
+	r.fill(min, max, func(p Vector2) bool {
+		return pointInPolygon(p, screen)
+	}, func(p Vector2) rl.Color {
+		// back into the unrotated dest rectangle, then across to src
+		w := r.toWorld(p)
+		dx, dy := w.X-float64(dest.X), w.Y-float64(dest.Y)
+		u := (dx*cos - dy*sin + origin.X) / float64(dest.Width)
+		v := (dx*sin + dy*cos + origin.Y) / float64(dest.Height)
+		// a negative size flips within the source rectangle, as in raylib
+		if src.Width < 0 {
+			u = 1 - u
+		}
+		if src.Height < 0 {
+			v = 1 - v
+		}
+		u = u*math.Abs(float64(src.Width)) + float64(src.X)
+		v = v*math.Abs(float64(src.Height)) + float64(src.Y)
+		pixel := color.NRGBAModel.Convert(img.At(int(math.Floor(u)), int(math.Floor(v)))).(color.NRGBA)
+		return rl.NewColor(
+			uint8(uint16(pixel.R)*uint16(tint.R)/255),
+			uint8(uint16(pixel.G)*uint16(tint.G)/255),
+			uint8(uint16(pixel.B)*uint16(tint.B)/255),
+			uint8(uint16(pixel.A)*uint16(tint.A)/255),
+		)
+	})
+}
+
+// DrawText does nothing, there are no fonts to draw with
+func (r *ImageRenderer) DrawText(text string, position Vector2, fontSize float64, c rl.Color) {}
+
+// fill blends shade(p) into every pixel between min and max whose centre p
+// is inside
+func (r *ImageRenderer) fill(min Vector2, max Vector2, inside func(p Vector2) bool, shade func(p Vector2) rl.Color) {
+	rect := image.Rect(int(math.Floor(min.X)), int(math.Floor(min.Y)), int(math.Ceil(max.X))+1, int(math.Ceil(max.Y))+1)
+	rect = rect.Intersect(r.img.Bounds())
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			p := NewVector2(float64(x)+0.5, float64(y)+0.5)
+			if inside(p) {
+				r.blend(x, y, shade(p))
+			}
+		}
+	}
+}
+
+// blend draws c over the pixel at x, y
+func (r *ImageRenderer) blend(x int, y int, c rl.Color) {
+	if c.A == 255 {
+		r.img.SetRGBA(x, y, color.RGBA{R: c.R, G: c.G, B: c.B, A: 255})
+		return
+	}
+	dst := r.img.RGBAAt(x, y)
+	a := uint32(c.A)
+	mix := func(s uint8, d uint8) uint8 {
+		return uint8((uint32(s)*a + uint32(d)*(255-a)) / 255)
+	}
+	r.img.SetRGBA(x, y, color.RGBA{
+		R: mix(c.R, dst.R),
+		G: mix(c.G, dst.G),
+		B: mix(c.B, dst.B),
+		A: uint8(a + uint32(dst.A)*(255-a)/255),
+	})
+}
+
+func bounds(points []Vector2) (Vector2, Vector2) {
+	min, max := points[0], points[0]
+	for _, p := range points[1:] {
+		min = NewVector2(math.Min(min.X, p.X), math.Min(min.Y, p.Y))
+		max = NewVector2(math.Max(max.X, p.X), math.Max(max.Y, p.Y))
+	}
+	return min, max
+}
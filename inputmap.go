@@ -0,0 +1,344 @@
+package raychip
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+type InputKind int
+
+const (
+	KeyInput InputKind = iota
+	MouseButtonInput
+	GamepadButtonInput
+	GamepadAxisInput
+)
+
+var inputKindNames = map[InputKind]string{
+	KeyInput:           "key",
+	MouseButtonInput:   "mouse_button",
+	GamepadButtonInput: "gamepad_button",
+	GamepadAxisInput:   "gamepad_axis",
+}
+
+func (k InputKind) String() string {
+	if name, ok := inputKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("InputKind(%d)", int(k))
+}
+
+func (k InputKind) MarshalText() ([]byte, error) {
+	if _, ok := inputKindNames[k]; !ok {
+		return nil, fmt.Errorf("unknown input kind %d", int(k))
+	}
+	return []byte(k.String()), nil
+}
+
+func (k *InputKind) UnmarshalText(text []byte) error {
+	for kind, name := range inputKindNames {
+		if name == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown input kind %q", text)
+}
+
+// AnyGamepad lets a gamepad binding respond to every connected gamepad
+const AnyGamepad int32 = -1
+
+// actionPressThreshold is how far an axis has to move for its action to be down
+const actionPressThreshold = 0.5
+
+// Binding ties an action to a single input. Buttons and keys give the
+// action a value of Scale while held, so "move_x" can bind KeyA with a scale
+// of -1 and KeyD with 1. Axes give their position times Scale.
+type Binding struct {
+	Kind    InputKind `json:"kind"`
+	Code    int32     `json:"code"`
+	Gamepad int32     `json:"gamepad,omitempty"`
+	Scale   float64   `json:"scale"`
+}
+
+func KeyBinding(key int32) Binding {
+	return Binding{Kind: KeyInput, Code: key, Scale: 1}
+}
+
+func MouseBinding(button rl.MouseButton) Binding {
+	return Binding{Kind: MouseButtonInput, Code: int32(button), Scale: 1}
+}
+
+func GamepadButtonBinding(gamepad int32, button int32) Binding {
+	return Binding{Kind: GamepadButtonInput, Code: button, Gamepad: gamepad, Scale: 1}
+}
+
+// GamepadAxisBinding binds one of raylib's gamepad axes. Sticks go from -1
+// to 1 and triggers from 0 to 1, both with the game's deadzones applied.
+func GamepadAxisBinding(gamepad int32, axis int32) Binding {
+	return Binding{Kind: GamepadAxisInput, Code: axis, Gamepad: gamepad, Scale: 1}
+}
+
+// Scaled returns a copy of the binding with a different scale
+func (b Binding) Scaled(scale float64) Binding {
+	b.Scale = scale
+	return b
+}
+
+// UnmarshalJSON defaults Scale to 1 when it is left out, like the binding
+// constructors do
+func (b *Binding) UnmarshalJSON(data []byte) error {
+	// binding has Binding's fields but not this method
+	type binding Binding
+	in := binding{Scale: 1}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*b = Binding(in)
+	return nil
+}
+
+// read gives the unscaled position of the bound input
+func (b Binding) read(keyboard KeyboardInputEvent, mouse MouseInputEvent, gamepads GamepadInputEvent) float64 {
+	var value float64
+	switch b.Kind {
+	case KeyInput:
+		if keyboard.IsKeyDown(b.Code) {
+			value = 1
+		}
+	case MouseButtonInput:
+		if mouse.IsButtonDown(rl.MouseButton(b.Code)) {
+			value = 1
+		}
+	case GamepadButtonInput, GamepadAxisInput:
+		// take whichever gamepad is pushed furthest
+		for _, gamepad := range gamepads.Gamepads {
+			if b.Gamepad != AnyGamepad && b.Gamepad != gamepad.Gamepad {
+				continue
+			}
+			v := gamepadValue(gamepad, b.Kind, b.Code)
+			if math.Abs(v) > math.Abs(value) {
+				value = v
+			}
+		}
+	}
+	return value
+}
+
+func gamepadValue(gamepad GamepadState, kind InputKind, code int32) float64 {
+	if kind == GamepadButtonInput {
+		if gamepad.IsButtonDown(code) {
+			return 1
+		}
+		return 0
+	}
+	switch code {
+	case rl.GamepadAxisLeftX:
+		return gamepad.LeftStick.X
+	case rl.GamepadAxisLeftY:
+		return gamepad.LeftStick.Y
+	case rl.GamepadAxisRightX:
+		return gamepad.RightStick.X
+	case rl.GamepadAxisRightY:
+		return gamepad.RightStick.Y
+	case rl.GamepadAxisLeftTrigger:
+		return gamepad.LeftTrigger
+	case rl.GamepadAxisRightTrigger:
+		return gamepad.RightTrigger
+	}
+	return 0
+}
+
+type actionState struct {
+	down     bool
+	pressed  bool
+	released bool
+	value    float64
+}
+
+// ActionEvent is published on "input.action" whenever an action is pressed,
+// released or its value changes
+type ActionEvent struct {
+	Action   string
+	Pressed  bool
+	Released bool
+	Down     bool
+	Value    float64
+}
+
+// InputMap maps named actions such as "jump" or "move_x" to the inputs that
+// trigger them, so games don't have to hard-code keys and players can rebind
+// them.
+type InputMap struct {
+	bindings map[string][]Binding
+	states   map[string]actionState
+}
+
+// Bind adds bindings to an action, creating the action if needed
+func (m *InputMap) Bind(action string, bindings ...Binding) {
+	if m.bindings == nil {
+		m.bindings = make(map[string][]Binding)
+		m.states = make(map[string]actionState)
+	}
+	m.bindings[action] = append(m.bindings[action], bindings...)
+}
+
+// Unbind removes an action and all its bindings
+func (m *InputMap) Unbind(action string) {
+	delete(m.bindings, action)
+	delete(m.states, action)
+}
+
+// Rebind replaces all of an action's bindings
+func (m *InputMap) Rebind(action string, bindings ...Binding) {
+	m.Unbind(action)
+	m.Bind(action, bindings...)
+}
+
+func (m InputMap) Bindings(action string) []Binding {
+	return append([]Binding(nil), m.bindings[action]...)
+}
+
+// Actions lists every bound action in alphabetical order
+func (m InputMap) Actions() []string {
+	actions := make([]string, 0, len(m.bindings))
+	for action := range m.bindings {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+func (m InputMap) ActionPressed(action string) bool {
+	return m.states[action].pressed
+}
+
+func (m InputMap) ActionReleased(action string) bool {
+	return m.states[action].released
+}
+
+func (m InputMap) ActionDown(action string) bool {
+	return m.states[action].down
+}
+
+// ActionValue is the sum of the action's bindings clamped to -1..1, e.g.
+// -1 while only KeyA is held for a "move_x" bound as above
+func (m InputMap) ActionValue(action string) float64 {
+	return m.states[action].value
+}
+
+func (m InputMap) MarshalJSON() ([]byte, error) {
+	bindings := m.bindings
+	if bindings == nil {
+		bindings = map[string][]Binding{}
+	}
+	return json.Marshal(bindings)
+}
+
+// UnmarshalJSON replaces every binding with the ones in data
+func (m *InputMap) UnmarshalJSON(data []byte) error {
+	var bindings map[string][]Binding
+	if err := json.Unmarshal(data, &bindings); err != nil {
+		return err
+	}
+	m.bindings = nil
+	m.states = nil
+	for action, actionBindings := range bindings {
+		m.Bind(action, actionBindings...)
+	}
+	return nil
+}
+
+// SaveBindings writes the bindings to a JSON file
+func (m InputMap) SaveBindings(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// LoadBindings replaces the bindings with ones saved by SaveBindings
+func (m *InputMap) LoadBindings(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, m)
+}
+
+func (m InputMap) hasBindings() bool {
+	return len(m.bindings) > 0
+}
+
+// update works out every action's state from this frame's input and returns
+// an event for each action that changed
+func (m *InputMap) update(keyboard KeyboardInputEvent, mouse MouseInputEvent, gamepads GamepadInputEvent) []ActionEvent {
+	var events []ActionEvent
+	for _, action := range m.Actions() {
+		var value float64
+		var down bool
+		for _, binding := range m.bindings[action] {
+			v := binding.read(keyboard, mouse, gamepads)
+			value += v * binding.Scale
+			down = down || math.Abs(v) >= actionPressThreshold
+		}
+		value = math.Max(-1, math.Min(value, 1))
+
+		last := m.states[action]
+		state := actionState{
+			down:     down,
+			pressed:  down && !last.down,
+			released: !down && last.down,
+			value:    value,
+		}
+		m.states[action] = state
+
+		if state.pressed || state.released || state.value != last.value {
+			events = append(events, ActionEvent{
+				Action:   action,
+				Pressed:  state.pressed,
+				Released: state.released,
+				Down:     state.down,
+				Value:    state.value,
+			})
+		}
+	}
+	return events
+}
+
+func (game *Game) InputMap() *InputMap {
+	return &game.inputMap
+}
+
+func (game Game) ActionPressed(action string) bool {
+	return game.inputMap.ActionPressed(action)
+}
+
+func (game Game) ActionReleased(action string) bool {
+	return game.inputMap.ActionReleased(action)
+}
+
+func (game Game) ActionDown(action string) bool {
+	return game.inputMap.ActionDown(action)
+}
+
+func (game Game) ActionValue(action string) float64 {
+	return game.inputMap.ActionValue(action)
+}
+
+// OnAction calls back every time the action changes, see ActionEvent
+func (game *Game) OnAction(action string, callback func(event ActionEvent)) SubscriptionID {
+	id := Subscribe(&game.EventBus, "input.action", func(event ActionEvent) {
+		if event.Action == action {
+			callback(event)
+		}
+	})
+
+	return id
+}
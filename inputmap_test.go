@@ -0,0 +1,114 @@
+package raychip
+
+import (
+	"encoding/json"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func keysDown(keys ...int32) KeyboardInputEvent {
+	input := KeyboardInputEvent{KeyStateMap: make(map[int32]KeyboardKeyState)}
+	for _, key := range keys {
+		input.KeyStateMap[key] = KeyboardKeyState{Down: true}
+	}
+	return input
+}
+
+func TestActionValue(t *testing.T) {
+	var m InputMap
+	m.Bind("move_x", KeyBinding(rl.KeyA).Scaled(-1), KeyBinding(rl.KeyD), GamepadAxisBinding(AnyGamepad, rl.GamepadAxisLeftX))
+	m.Bind("jump", KeyBinding(rl.KeySpace))
+
+	tests := []struct {
+		name     string
+		keyboard KeyboardInputEvent
+		gamepads GamepadInputEvent
+		value    float64
+	}{
+		{"nothing held", keysDown(), GamepadInputEvent{}, 0},
+		{"left", keysDown(rl.KeyA), GamepadInputEvent{}, -1},
+		{"right", keysDown(rl.KeyD), GamepadInputEvent{}, 1},
+		{"both cancel out", keysDown(rl.KeyA, rl.KeyD), GamepadInputEvent{}, 0},
+		{"stick", keysDown(), GamepadInputEvent{Gamepads: []GamepadState{{Gamepad: 2, LeftStick: NewVector2(0.25, 0)}}}, 0.25},
+		{"clamped", keysDown(rl.KeyD), GamepadInputEvent{Gamepads: []GamepadState{{Gamepad: 0, LeftStick: NewVector2(0.5, 0)}}}, 1},
+	}
+	for _, test := range tests {
+		m.update(test.keyboard, MouseInputEvent{}, test.gamepads)
+		if got := m.ActionValue("move_x"); math.Abs(got-test.value) > 1e-9 {
+			t.Errorf("%s: move_x is %v, want %v", test.name, got, test.value)
+		}
+	}
+}
+
+func TestActionPressAndRelease(t *testing.T) {
+	var m InputMap
+	m.Bind("jump", KeyBinding(rl.KeySpace))
+
+	frames := []struct {
+		keyboard                KeyboardInputEvent
+		pressed, down, released bool
+		events                  int
+	}{
+		{keysDown(rl.KeySpace), true, true, false, 1},
+		{keysDown(rl.KeySpace), false, true, false, 0},
+		{keysDown(), false, false, true, 1},
+		{keysDown(), false, false, false, 0},
+	}
+	for i, frame := range frames {
+		events := m.update(frame.keyboard, MouseInputEvent{}, GamepadInputEvent{})
+		if m.ActionPressed("jump") != frame.pressed || m.ActionDown("jump") != frame.down || m.ActionReleased("jump") != frame.released {
+			t.Errorf("frame %d: pressed %v down %v released %v", i, m.ActionPressed("jump"), m.ActionDown("jump"), m.ActionReleased("jump"))
+		}
+		if len(events) != frame.events {
+			t.Errorf("frame %d: got %d action events, want %d", i, len(events), frame.events)
+		}
+	}
+}
+
+func TestBindingsRoundTrip(t *testing.T) {
+	var m InputMap
+	m.Bind("move_x", KeyBinding(rl.KeyA).Scaled(-1), KeyBinding(rl.KeyD))
+	m.Bind("fire", MouseBinding(rl.MouseButtonLeft), GamepadButtonBinding(1, rl.GamepadButtonRightFaceDown))
+	m.Bind("look_y", GamepadAxisBinding(AnyGamepad, rl.GamepadAxisRightY).Scaled(0.5))
+
+	path := filepath.Join(t.TempDir(), "bindings.json")
+	if err := m.SaveBindings(path); err != nil {
+		t.Fatalf("SaveBindings: %v", err)
+	}
+	var loaded InputMap
+	loaded.Bind("stale", KeyBinding(rl.KeyQ))
+	if err := loaded.LoadBindings(path); err != nil {
+		t.Fatalf("LoadBindings: %v", err)
+	}
+
+	if !reflect.DeepEqual(loaded.Actions(), m.Actions()) {
+		t.Fatalf("loaded actions %v, want %v", loaded.Actions(), m.Actions())
+	}
+	for _, action := range m.Actions() {
+		if !reflect.DeepEqual(loaded.Bindings(action), m.Bindings(action)) {
+			t.Errorf("%s: loaded %+v, want %+v", action, loaded.Bindings(action), m.Bindings(action))
+		}
+	}
+}
+
+func TestBindingScaleDefaultsToOne(t *testing.T) {
+	var m InputMap
+	if err := json.Unmarshal([]byte(`{"jump": [{"kind": "key", "code": 32}], "back": [{"kind": "key", "code": 65, "scale": -1}]}`), &m); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got := m.Bindings("jump"); len(got) != 1 || got[0] != KeyBinding(rl.KeySpace) {
+		t.Errorf("jump bindings are %+v", got)
+	}
+	if got := m.Bindings("back"); len(got) != 1 || got[0].Scale != -1 {
+		t.Errorf("back bindings are %+v", got)
+	}
+
+	var bad InputMap
+	if err := json.Unmarshal([]byte(`{"jump": [{"kind": "joystick"}]}`), &bad); err == nil {
+		t.Errorf("unknown input kind loaded without an error")
+	}
+}
@@ -1,16 +1,26 @@
 package raychip
 
 import (
-	"fmt"
+	"math"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
+// maxGamepads is how many gamepads raylib supports at once
+const maxGamepads = 4
+
 type GameInputs struct {
 	MouseInputEnable    bool
 	KeyboardInputEnable bool
 	GamepadInputEnable  bool
 	InputPublishers     []*Publisher
+
+	stickDeadzone     float64
+	triggerDeadzone   float64
+	gamepadsConnected [maxGamepads]bool
+	// gamepadNames is the last name seen in each slot, so disconnects can
+	// say which gamepad went away
+	gamepadNames [maxGamepads]string
 }
 
 func (g *Game) EnableMouseInput() {
@@ -22,17 +32,26 @@ func (g *Game) DisableMouseInput() {
 }
 
 func (g *Game) EnableKeyboardInput() {
-	fmt.Println("Keyboard inputs not implemented!")
-	if !g.inputs.KeyboardInputEnable {
-		g.inputs.KeyboardInputEnable = true
-	}
+	g.inputs.KeyboardInputEnable = true
+}
+
+func (g *Game) DisableKeyboardInput() {
+	g.inputs.KeyboardInputEnable = false
 }
 
 func (g *Game) EnableGamepadInput() {
-	fmt.Println("Keyboard inputs not implemented!")
-	if !g.inputs.GamepadInputEnable {
-		g.inputs.GamepadInputEnable = true
-	}
+	g.inputs.GamepadInputEnable = true
+}
+
+func (g *Game) DisableGamepadInput() {
+	g.inputs.GamepadInputEnable = false
+}
+
+// SetGamepadDeadzones sets how far, from 0 to 1, the sticks and triggers
+// have to move before they register. Stick deadzones are radial.
+func (g *Game) SetGamepadDeadzones(stick float64, trigger float64) {
+	g.inputs.stickDeadzone = stick
+	g.inputs.triggerDeadzone = trigger
 }
 
 type MouseButtonState struct {
@@ -44,7 +63,9 @@ type MouseButtonState struct {
 
 type MouseInputEvent struct {
 	ButtonStateMap map[rl.MouseButton]MouseButtonState
+	// Position is in world coordinates, ScreenPosition is the raw mouse position
 	Position       Vector2
+	ScreenPosition Vector2
 }
 
 func (m MouseInputEvent) IsButtonReleased(button rl.MouseButton) bool {
@@ -63,14 +84,141 @@ func (m MouseInputEvent) IsButtonDown(button rl.MouseButton) bool {
 	return m.ButtonStateMap[button].Down
 }
 
+type KeyboardKeyState struct {
+	Pressed  bool
+	Released bool
+	Down     bool
+	Repeat   bool
+}
+
+type KeyModifiers struct {
+	Shift   bool
+	Control bool
+	Alt     bool
+	Super   bool
+}
+
+// KeyboardInputEvent is published on "input.keyboard" every frame while
+// keyboard input is enabled. Keys that are up are left out of KeyStateMap.
 type KeyboardInputEvent struct {
-	Key   int
-	State bool
+	KeyStateMap map[int32]KeyboardKeyState
+	Modifiers   KeyModifiers
+	// characters typed this frame, in order
+	Chars []rune
+}
+
+func (k KeyboardInputEvent) IsKeyPressed(key int32) bool {
+	return k.KeyStateMap[key].Pressed
+}
+
+func (k KeyboardInputEvent) IsKeyReleased(key int32) bool {
+	return k.KeyStateMap[key].Released
+}
+
+func (k KeyboardInputEvent) IsKeyDown(key int32) bool {
+	return k.KeyStateMap[key].Down
+}
+
+func (k KeyboardInputEvent) IsKeyUp(key int32) bool {
+	return !k.KeyStateMap[key].Down
+}
+
+func (k KeyboardInputEvent) IsKeyRepeat(key int32) bool {
+	return k.KeyStateMap[key].Repeat
 }
 
+type KeyState int
+
+const (
+	KeyUp KeyState = iota
+	KeyDown
+	KeyPressed
+	KeyReleased
+	KeyRepeat
+)
+
+// OnKey calls back on every keyboard input event where the key is in the
+// given state. Keyboard input has to be enabled.
+func (g *Game) OnKey(key int32, state KeyState, callback func()) SubscriptionID {
+	id := Subscribe(&g.EventBus, "input.keyboard", func(input KeyboardInputEvent) {
+		var matched = false
+		switch state {
+		case KeyUp:
+			matched = input.IsKeyUp(key)
+		case KeyDown:
+			matched = input.IsKeyDown(key)
+		case KeyPressed:
+			matched = input.IsKeyPressed(key)
+		case KeyReleased:
+			matched = input.IsKeyReleased(key)
+		case KeyRepeat:
+			matched = input.IsKeyRepeat(key)
+		}
+
+		if matched {
+			callback()
+		}
+	})
+
+	return id
+}
+
+type GamepadButtonState struct {
+	Pressed  bool
+	Released bool
+	Up       bool
+	Down     bool
+}
+
+// GamepadState is one gamepad's buttons and axes. Sticks and triggers have
+// had the deadzones applied, sticks go from -1 to 1 and triggers 0 to 1.
+type GamepadState struct {
+	Gamepad        int32
+	Name           string
+	ButtonStateMap map[int32]GamepadButtonState
+	LeftStick      Vector2
+	RightStick     Vector2
+	LeftTrigger    float64
+	RightTrigger   float64
+}
+
+func (g GamepadState) IsButtonPressed(button int32) bool {
+	return g.ButtonStateMap[button].Pressed
+}
+
+func (g GamepadState) IsButtonReleased(button int32) bool {
+	return g.ButtonStateMap[button].Released
+}
+
+func (g GamepadState) IsButtonUp(button int32) bool {
+	return g.ButtonStateMap[button].Up
+}
+
+func (g GamepadState) IsButtonDown(button int32) bool {
+	return g.ButtonStateMap[button].Down
+}
+
+// GamepadInputEvent is published on "input.gamepad" every frame while
+// gamepad input is enabled, with the state of every connected gamepad
 type GamepadInputEvent struct {
-	Button int
-	State  bool
+	Gamepads []GamepadState
+}
+
+func (g GamepadInputEvent) Gamepad(gamepad int32) (GamepadState, bool) {
+	for _, state := range g.Gamepads {
+		if state.Gamepad == gamepad {
+			return state, true
+		}
+	}
+	return GamepadState{}, false
+}
+
+// GamepadConnectionEvent is published on "input.gamepad.connection" when a
+// gamepad is plugged in or unplugged
+type GamepadConnectionEvent struct {
+	Gamepad   int32
+	Name      string
+	Connected bool
 }
 
 func getMouseInputEvent() MouseInputEvent {
@@ -80,6 +228,7 @@ func getMouseInputEvent() MouseInputEvent {
 	mouseInputEvent := MouseInputEvent{
 		ButtonStateMap: make(map[rl.MouseButton]MouseButtonState),
 		Position:       Vector2FromRaylib(mousePos),
+		ScreenPosition: Vector2FromRaylib(mousePos),
 	}
 
 	for _, button := range buttons {
@@ -107,10 +256,175 @@ func getMouseInputEvent() MouseInputEvent {
 	return mouseInputEvent
 }
 
-// TODO:
-// func getKeyboardInputEvent() KeyboardInputEvent {
-// }
+// keyboardKeys is every key raylib knows about on desktop
+var keyboardKeys = func() []int32 {
+	var keys []int32
+	ranges := [][2]int32{
+		{rl.KeySpace, rl.KeySpace},
+		{rl.KeyApostrophe, rl.KeyApostrophe},
+		{rl.KeyComma, rl.KeyNine},
+		{rl.KeySemicolon, rl.KeySemicolon},
+		{rl.KeyEqual, rl.KeyEqual},
+		{rl.KeyA, rl.KeyRightBracket},
+		{rl.KeyGrave, rl.KeyGrave},
+		{rl.KeyEscape, rl.KeyEnd},
+		{rl.KeyCapsLock, rl.KeyPause},
+		{rl.KeyF1, rl.KeyF12},
+		{rl.KeyKp0, rl.KeyKpEqual},
+		{rl.KeyLeftShift, rl.KeyKbMenu},
+	}
+	for _, r := range ranges {
+		for key := r[0]; key <= r[1]; key++ {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}()
+
+// modifiers works out which modifiers are held from either the left or right key
+func (k KeyboardInputEvent) modifiers() KeyModifiers {
+	return KeyModifiers{
+		Shift:   k.IsKeyDown(rl.KeyLeftShift) || k.IsKeyDown(rl.KeyRightShift),
+		Control: k.IsKeyDown(rl.KeyLeftControl) || k.IsKeyDown(rl.KeyRightControl),
+		Alt:     k.IsKeyDown(rl.KeyLeftAlt) || k.IsKeyDown(rl.KeyRightAlt),
+		Super:   k.IsKeyDown(rl.KeyLeftSuper) || k.IsKeyDown(rl.KeyRightSuper),
+	}
+}
+
+func getKeyboardInputEvent() KeyboardInputEvent {
+	keyboardInputEvent := KeyboardInputEvent{
+		KeyStateMap: make(map[int32]KeyboardKeyState),
+	}
+
+	for _, key := range keyboardKeys {
+		state := KeyboardKeyState{
+			Pressed:  rl.IsKeyPressed(key),
+			Released: rl.IsKeyReleased(key),
+			Down:     rl.IsKeyDown(key),
+			Repeat:   rl.IsKeyPressedRepeat(key),
+		}
+		if state != (KeyboardKeyState{}) {
+			keyboardInputEvent.KeyStateMap[key] = state
+		}
+	}
+
+	keyboardInputEvent.Modifiers = keyboardInputEvent.modifiers()
+
+	for char := rl.GetCharPressed(); char != 0; char = rl.GetCharPressed() {
+		keyboardInputEvent.Chars = append(keyboardInputEvent.Chars, rune(char))
+	}
+
+	return keyboardInputEvent
+}
+
+func getGamepadInputEvent(stickDeadzone float64, triggerDeadzone float64) GamepadInputEvent {
+	var gamepadInputEvent GamepadInputEvent
+
+	for gamepad := int32(0); gamepad < maxGamepads; gamepad++ {
+		if !rl.IsGamepadAvailable(gamepad) {
+			continue
+		}
+
+		state := GamepadState{
+			Gamepad:        gamepad,
+			Name:           rl.GetGamepadName(gamepad),
+			ButtonStateMap: make(map[int32]GamepadButtonState),
+		}
+		for button := int32(rl.GamepadButtonLeftFaceUp); button <= rl.GamepadButtonRightThumb; button++ {
+			state.ButtonStateMap[button] = GamepadButtonState{
+				Pressed:  rl.IsGamepadButtonPressed(gamepad, button),
+				Released: rl.IsGamepadButtonReleased(gamepad, button),
+				Up:       rl.IsGamepadButtonUp(gamepad, button),
+				Down:     rl.IsGamepadButtonDown(gamepad, button),
+			}
+		}
 
-// TODO:
-// func getGamepadInputEvent() GamepadInputEvent {
-// }
+		axis := func(axis int32) float64 {
+			return float64(rl.GetGamepadAxisMovement(gamepad, axis))
+		}
+		state.LeftStick = applyStickDeadzone(NewVector2(axis(rl.GamepadAxisLeftX), axis(rl.GamepadAxisLeftY)), stickDeadzone)
+		state.RightStick = applyStickDeadzone(NewVector2(axis(rl.GamepadAxisRightX), axis(rl.GamepadAxisRightY)), stickDeadzone)
+		// triggers rest at -1
+		state.LeftTrigger = applyDeadzone((axis(rl.GamepadAxisLeftTrigger)+1)/2, triggerDeadzone)
+		state.RightTrigger = applyDeadzone((axis(rl.GamepadAxisRightTrigger)+1)/2, triggerDeadzone)
+
+		gamepadInputEvent.Gamepads = append(gamepadInputEvent.Gamepads, state)
+	}
+
+	return gamepadInputEvent
+}
+
+// applyStickDeadzone zeroes a stick inside the deadzone and rescales the rest
+// so it still goes smoothly from 0 to 1
+func applyStickDeadzone(stick Vector2, deadzone float64) Vector2 {
+	magnitude := math.Hypot(stick.X, stick.Y)
+	if magnitude <= deadzone || magnitude == 0 {
+		return Vector2{}
+	}
+	scale := math.Min((magnitude-deadzone)/(1-deadzone), 1) / magnitude
+	return NewVector2(stick.X*scale, stick.Y*scale)
+}
+
+func applyDeadzone(value float64, deadzone float64) float64 {
+	if value <= deadzone {
+		return 0
+	}
+	return math.Min((value-deadzone)/(1-deadzone), 1)
+}
+
+// publishGamepadConnections compares the connected gamepads with last frame's
+func (g *Game) publishGamepadConnections(input GamepadInputEvent) {
+	var connected [maxGamepads]bool
+	for _, state := range input.Gamepads {
+		if state.Gamepad < 0 || state.Gamepad >= maxGamepads {
+			continue
+		}
+		connected[state.Gamepad] = true
+		g.inputs.gamepadNames[state.Gamepad] = state.Name
+	}
+
+	for gamepad := int32(0); gamepad < maxGamepads; gamepad++ {
+		if connected[gamepad] != g.inputs.gamepadsConnected[gamepad] {
+			publishIfSubscribed(&g.EventBus, "input.gamepad.connection", GamepadConnectionEvent{
+				Gamepad:   gamepad,
+				Name:      g.inputs.gamepadNames[gamepad],
+				Connected: connected[gamepad],
+			})
+		}
+	}
+	g.inputs.gamepadsConnected = connected
+}
+
+// InputFrame is everything read from the input devices in one frame, along
+// with how long the frame took
+type InputFrame struct {
+	FrameTime float64
+	Mouse     MouseInputEvent
+	Keyboard  KeyboardInputEvent
+	Gamepad   GamepadInputEvent
+}
+
+// readInput gets this frame's input from the replay if there is one, or the
+// game's InputSource otherwise, and adds it to the recording. It fails if the
+// game has no InputSource.
+func (g *Game) readInput() (InputFrame, bool) {
+	var input InputFrame
+	if g.IsReplaying() {
+		input = g.replay[g.replayIndex]
+		g.replayIndex++
+		if frames := len(g.replay); g.replayIndex == frames {
+			g.StopReplay()
+			publishIfSubscribed(&g.EventBus, "input.replay.end", ReplayEnded{Frames: frames})
+		}
+	} else if g.inputSource != nil {
+		input = g.inputSource.ReadInput(g)
+	} else {
+		return input, false
+	}
+	input.FrameTime = g.frameTime
+
+	if g.IsRecording() {
+		g.recorder.write(input)
+	}
+	return input, true
+}
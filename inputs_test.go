@@ -0,0 +1,121 @@
+package raychip
+
+import (
+	"math"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func TestKeyStateMap(t *testing.T) {
+	input := KeyboardInputEvent{
+		KeyStateMap: map[int32]KeyboardKeyState{
+			rl.KeySpace: {Pressed: true, Down: true},
+			rl.KeyA:     {Down: true, Repeat: true},
+			rl.KeyB:     {Released: true},
+		},
+	}
+
+	tests := []struct {
+		key                                    int32
+		pressed, released, down, up, repeating bool
+	}{
+		{rl.KeySpace, true, false, true, false, false},
+		{rl.KeyA, false, false, true, false, true},
+		{rl.KeyB, false, true, false, true, false},
+		// keys that are up are left out of the map
+		{rl.KeyC, false, false, false, true, false},
+	}
+	for _, test := range tests {
+		if input.IsKeyPressed(test.key) != test.pressed ||
+			input.IsKeyReleased(test.key) != test.released ||
+			input.IsKeyDown(test.key) != test.down ||
+			input.IsKeyUp(test.key) != test.up ||
+			input.IsKeyRepeat(test.key) != test.repeating {
+			t.Errorf("key %d: wrong state from %+v", test.key, input.KeyStateMap[test.key])
+		}
+	}
+}
+
+func TestKeyModifiers(t *testing.T) {
+	tests := []struct {
+		keys []int32
+		want KeyModifiers
+	}{
+		{nil, KeyModifiers{}},
+		{[]int32{rl.KeyLeftShift}, KeyModifiers{Shift: true}},
+		{[]int32{rl.KeyRightShift, rl.KeyRightAlt}, KeyModifiers{Shift: true, Alt: true}},
+		{[]int32{rl.KeyLeftControl, rl.KeyRightSuper}, KeyModifiers{Control: true, Super: true}},
+		{[]int32{rl.KeyA}, KeyModifiers{}},
+	}
+	for _, test := range tests {
+		input := KeyboardInputEvent{KeyStateMap: make(map[int32]KeyboardKeyState)}
+		for _, key := range test.keys {
+			input.KeyStateMap[key] = KeyboardKeyState{Down: true}
+		}
+		if got := input.modifiers(); got != test.want {
+			t.Errorf("keys %v: got %+v, want %+v", test.keys, got, test.want)
+		}
+	}
+}
+
+func TestOnKey(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	var pressed, up int
+	game.OnKey(rl.KeySpace, KeyPressed, func() { pressed++ })
+	game.OnKey(rl.KeySpace, KeyUp, func() { up++ })
+
+	game.EventBus.Publish("input.keyboard", KeyboardInputEvent{
+		KeyStateMap: map[int32]KeyboardKeyState{rl.KeySpace: {Pressed: true, Down: true}},
+	})
+	game.EventBus.Publish("input.keyboard", KeyboardInputEvent{})
+
+	if pressed != 1 || up != 1 {
+		t.Errorf("got %d presses and %d ups, want 1 and 1", pressed, up)
+	}
+}
+
+func TestGamepadDisconnectKeepsName(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	var events []GamepadConnectionEvent
+	game.EventBus.CreateSubscription("input.gamepad.connection", GamepadConnectionEvent{}, func(e GamepadConnectionEvent) {
+		events = append(events, e)
+	})
+
+	game.publishGamepadConnections(GamepadInputEvent{Gamepads: []GamepadState{{Gamepad: 1, Name: "Xbox Controller"}}})
+	game.publishGamepadConnections(GamepadInputEvent{Gamepads: []GamepadState{{Gamepad: 1, Name: "Xbox Controller"}}})
+	game.publishGamepadConnections(GamepadInputEvent{})
+
+	if len(events) != 2 {
+		t.Fatalf("got %d connection events, want 2", len(events))
+	}
+	if !events[0].Connected || events[0].Gamepad != 1 {
+		t.Errorf("connect event is %+v", events[0])
+	}
+	if events[1].Connected || events[1].Gamepad != 1 || events[1].Name != "Xbox Controller" {
+		t.Errorf("disconnect event is %+v", events[1])
+	}
+}
+
+func TestGamepadDeadzones(t *testing.T) {
+	if got := applyStickDeadzone(NewVector2(0.1, 0.1), 0.2); got != (Vector2{}) {
+		t.Errorf("stick inside the deadzone is %v", got)
+	}
+	// half way between the deadzone and the edge comes out at half
+	got := applyStickDeadzone(NewVector2(0, 0.6), 0.2)
+	if math.Abs(got.X) > 1e-9 || math.Abs(got.Y-0.5) > 1e-9 {
+		t.Errorf("stick is %v, want (0, 0.5)", got)
+	}
+	// the corners of the square raylib reports are capped at 1
+	got = applyStickDeadzone(NewVector2(1, 1), 0.2)
+	if math.Abs(math.Hypot(got.X, got.Y)-1) > 1e-9 {
+		t.Errorf("full stick has length %v", math.Hypot(got.X, got.Y))
+	}
+
+	tests := []struct{ value, want float64 }{{0.05, 0}, {0.1, 0}, {0.55, 0.5}, {1, 1}}
+	for _, test := range tests {
+		if got := applyDeadzone(test.value, 0.1); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("trigger at %v is %v, want %v", test.value, got, test.want)
+		}
+	}
+}
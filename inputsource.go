@@ -0,0 +1,266 @@
+package raychip
+
+import (
+	"sort"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// InputSource is where a game reads its input from each frame. The game
+// fills in the frame's FrameTime itself.
+type InputSource interface {
+	ReadInput(game *Game) InputFrame
+}
+
+func (game *Game) SetInputSource(source InputSource) {
+	game.inputSource = source
+}
+
+func (game Game) InputSource() InputSource {
+	return game.inputSource
+}
+
+// RaylibInput reads the mouse, keyboard and gamepads through raylib. It is
+// the input source of games made with NewGame.
+type RaylibInput struct{}
+
+func (RaylibInput) ReadInput(game *Game) InputFrame {
+	// a recording needs everything so it can be replayed whatever is enabled
+	readAll := game.IsRecording() || game.inputMap.hasBindings()
+	input := InputFrame{Mouse: getMouseInputEvent()}
+	if game.inputs.KeyboardInputEnable || readAll {
+		input.Keyboard = getKeyboardInputEvent()
+	}
+	if game.inputs.GamepadInputEnable || readAll {
+		input.Gamepad = getGamepadInputEvent(game.inputs.stickDeadzone, game.inputs.triggerDeadzone)
+	}
+	return input
+}
+
+// buttonScript tracks a set of scripted buttons or keys from frame to frame
+type buttonScript struct {
+	down     map[int32]bool
+	pressed  map[int32]bool
+	released map[int32]bool
+	// buttons let go of before their press was read, released next frame
+	releaseNext map[int32]bool
+}
+
+func newButtonScript() buttonScript {
+	return buttonScript{
+		down:        make(map[int32]bool),
+		pressed:     make(map[int32]bool),
+		released:    make(map[int32]bool),
+		releaseNext: make(map[int32]bool),
+	}
+}
+
+func (b *buttonScript) press(code int32) {
+	if !b.down[code] {
+		b.pressed[code] = true
+	}
+	b.down[code] = true
+	delete(b.releaseNext, code)
+}
+
+func (b *buttonScript) release(code int32) {
+	if b.pressed[code] {
+		b.releaseNext[code] = true
+	} else if b.down[code] {
+		b.released[code] = true
+		delete(b.down, code)
+	}
+}
+
+// codes is every button that is down or changed this frame
+func (b buttonScript) codes() []int32 {
+	var codes []int32
+	for _, set := range []map[int32]bool{b.down, b.pressed, b.released} {
+		for code := range set {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// advance moves on to the next frame
+func (b *buttonScript) advance() {
+	clear(b.pressed)
+	clear(b.released)
+	for code := range b.releaseNext {
+		b.released[code] = true
+		delete(b.down, code)
+	}
+	clear(b.releaseNext)
+}
+
+type scriptedGamepad struct {
+	name    string
+	buttons buttonScript
+	axes    map[int32]float64
+}
+
+// ScriptedInput is an input source driven from code, for tests and demos.
+// Presses and releases show up in the next frame the game reads, and a
+// button pressed and released before then is held for one frame, so Click
+// and TapKey give one frame with Pressed and the next with Released. It is
+// the input source of games made with NewHeadlessGame.
+type ScriptedInput struct {
+	mousePosition Vector2
+	mouse         buttonScript
+	keys          buttonScript
+	chars         []rune
+	gamepads      map[int32]*scriptedGamepad
+}
+
+func NewScriptedInput() *ScriptedInput {
+	return &ScriptedInput{
+		mouse:    newButtonScript(),
+		keys:     newButtonScript(),
+		gamepads: make(map[int32]*scriptedGamepad),
+	}
+}
+
+// MoveMouse moves the mouse to a point on the screen
+func (s *ScriptedInput) MoveMouse(x float64, y float64) {
+	s.mousePosition = NewVector2(x, y)
+}
+
+func (s *ScriptedInput) PressMouse(button rl.MouseButton) {
+	s.mouse.press(int32(button))
+}
+
+func (s *ScriptedInput) ReleaseMouse(button rl.MouseButton) {
+	s.mouse.release(int32(button))
+}
+
+func (s *ScriptedInput) Click(button rl.MouseButton) {
+	s.PressMouse(button)
+	s.ReleaseMouse(button)
+}
+
+// ClickAt moves the mouse to a point on the screen and clicks there
+func (s *ScriptedInput) ClickAt(x float64, y float64, button rl.MouseButton) {
+	s.MoveMouse(x, y)
+	s.Click(button)
+}
+
+func (s *ScriptedInput) PressKey(key int32) {
+	s.keys.press(key)
+}
+
+func (s *ScriptedInput) ReleaseKey(key int32) {
+	s.keys.release(key)
+}
+
+func (s *ScriptedInput) TapKey(key int32) {
+	s.PressKey(key)
+	s.ReleaseKey(key)
+}
+
+// TypeText adds characters to the next keyboard event's Chars
+func (s *ScriptedInput) TypeText(text string) {
+	s.chars = append(s.chars, []rune(text)...)
+}
+
+// ConnectGamepad plugs in a gamepad. Pressing a button or moving an axis of
+// a gamepad that isn't connected connects it too.
+func (s *ScriptedInput) ConnectGamepad(gamepad int32, name string) {
+	if _, ok := s.gamepads[gamepad]; ok {
+		s.gamepads[gamepad].name = name
+		return
+	}
+	s.gamepads[gamepad] = &scriptedGamepad{
+		name:    name,
+		buttons: newButtonScript(),
+		axes:    make(map[int32]float64),
+	}
+}
+
+func (s *ScriptedInput) DisconnectGamepad(gamepad int32) {
+	delete(s.gamepads, gamepad)
+}
+
+func (s *ScriptedInput) gamepad(gamepad int32) *scriptedGamepad {
+	if _, ok := s.gamepads[gamepad]; !ok {
+		s.ConnectGamepad(gamepad, "Scripted gamepad")
+	}
+	return s.gamepads[gamepad]
+}
+
+func (s *ScriptedInput) PressGamepadButton(gamepad int32, button int32) {
+	s.gamepad(gamepad).buttons.press(button)
+}
+
+func (s *ScriptedInput) ReleaseGamepadButton(gamepad int32, button int32) {
+	s.gamepad(gamepad).buttons.release(button)
+}
+
+// SetGamepadAxis sets one of raylib's gamepad axes. The value is used as is,
+// without the game's deadzones, with sticks from -1 to 1 and triggers from
+// 0 to 1.
+func (s *ScriptedInput) SetGamepadAxis(gamepad int32, axis int32, value float64) {
+	s.gamepad(gamepad).axes[axis] = value
+}
+
+func (s *ScriptedInput) ReadInput(game *Game) InputFrame {
+	var input InputFrame
+
+	input.Mouse = MouseInputEvent{
+		ButtonStateMap: make(map[rl.MouseButton]MouseButtonState),
+		Position:       s.mousePosition,
+		ScreenPosition: s.mousePosition,
+	}
+	buttons := append([]int32{int32(rl.MouseButtonLeft), int32(rl.MouseButtonRight), int32(rl.MouseButtonMiddle)}, s.mouse.codes()...)
+	for _, button := range buttons {
+		input.Mouse.ButtonStateMap[rl.MouseButton(button)] = MouseButtonState{
+			Pressed:  s.mouse.pressed[button],
+			Released: s.mouse.released[button],
+			Up:       !s.mouse.down[button],
+			Down:     s.mouse.down[button],
+		}
+	}
+
+	input.Keyboard = KeyboardInputEvent{
+		KeyStateMap: make(map[int32]KeyboardKeyState),
+		Chars:       s.chars,
+	}
+	for _, key := range s.keys.codes() {
+		input.Keyboard.KeyStateMap[key] = KeyboardKeyState{
+			Pressed:  s.keys.pressed[key],
+			Released: s.keys.released[key],
+			Down:     s.keys.down[key],
+		}
+	}
+	input.Keyboard.Modifiers = input.Keyboard.modifiers()
+
+	for id, gamepad := range s.gamepads {
+		state := GamepadState{
+			Gamepad:        id,
+			Name:           gamepad.name,
+			ButtonStateMap: make(map[int32]GamepadButtonState),
+			LeftStick:      NewVector2(gamepad.axes[rl.GamepadAxisLeftX], gamepad.axes[rl.GamepadAxisLeftY]),
+			RightStick:     NewVector2(gamepad.axes[rl.GamepadAxisRightX], gamepad.axes[rl.GamepadAxisRightY]),
+			LeftTrigger:    gamepad.axes[rl.GamepadAxisLeftTrigger],
+			RightTrigger:   gamepad.axes[rl.GamepadAxisRightTrigger],
+		}
+		for button := int32(rl.GamepadButtonLeftFaceUp); button <= rl.GamepadButtonRightThumb; button++ {
+			state.ButtonStateMap[button] = GamepadButtonState{
+				Pressed:  gamepad.buttons.pressed[button],
+				Released: gamepad.buttons.released[button],
+				Up:       !gamepad.buttons.down[button],
+				Down:     gamepad.buttons.down[button],
+			}
+		}
+		input.Gamepad.Gamepads = append(input.Gamepad.Gamepads, state)
+		gamepad.buttons.advance()
+	}
+	sort.Slice(input.Gamepad.Gamepads, func(i, j int) bool {
+		return input.Gamepad.Gamepads[i].Gamepad < input.Gamepad.Gamepads[j].Gamepad
+	})
+
+	s.mouse.advance()
+	s.keys.advance()
+	s.chars = nil
+	return input
+}
@@ -0,0 +1,77 @@
+package raychip
+
+import (
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func TestScriptedClickSpansTwoFrames(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	input := game.InputSource().(*ScriptedInput)
+	input.ClickAt(10, 20, rl.MouseButtonLeft)
+	input.TapKey(rl.KeySpace)
+
+	first := input.ReadInput(&game)
+	second := input.ReadInput(&game)
+	third := input.ReadInput(&game)
+
+	if !first.Mouse.IsButtonPressed(rl.MouseButtonLeft) || !first.Mouse.IsButtonDown(rl.MouseButtonLeft) || first.Mouse.ScreenPosition != NewVector2(10, 20) {
+		t.Errorf("first frame mouse is %+v", first.Mouse)
+	}
+	if !second.Mouse.IsButtonReleased(rl.MouseButtonLeft) || !second.Mouse.IsButtonUp(rl.MouseButtonLeft) {
+		t.Errorf("second frame mouse is %+v", second.Mouse)
+	}
+	if third.Mouse.IsButtonReleased(rl.MouseButtonLeft) || !third.Mouse.IsButtonUp(rl.MouseButtonLeft) {
+		t.Errorf("third frame mouse is %+v", third.Mouse)
+	}
+
+	if !first.Keyboard.IsKeyPressed(rl.KeySpace) || !second.Keyboard.IsKeyReleased(rl.KeySpace) || len(third.Keyboard.KeyStateMap) != 0 {
+		t.Errorf("tapped space read as %+v, %+v, %+v", first.Keyboard.KeyStateMap, second.Keyboard.KeyStateMap, third.Keyboard.KeyStateMap)
+	}
+}
+
+func TestScriptedHeldKey(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	input := game.InputSource().(*ScriptedInput)
+	input.PressKey(rl.KeyLeftShift)
+
+	first := input.ReadInput(&game)
+	second := input.ReadInput(&game)
+	input.ReleaseKey(rl.KeyLeftShift)
+	third := input.ReadInput(&game)
+
+	if !first.Keyboard.IsKeyPressed(rl.KeyLeftShift) || !first.Keyboard.Modifiers.Shift {
+		t.Errorf("first frame keyboard is %+v", first.Keyboard)
+	}
+	if second.Keyboard.IsKeyPressed(rl.KeyLeftShift) || !second.Keyboard.IsKeyDown(rl.KeyLeftShift) {
+		t.Errorf("second frame keyboard is %+v", second.Keyboard)
+	}
+	if !third.Keyboard.IsKeyReleased(rl.KeyLeftShift) || third.Keyboard.Modifiers.Shift {
+		t.Errorf("third frame keyboard is %+v", third.Keyboard)
+	}
+}
+
+func TestScriptedGamepad(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	input := game.InputSource().(*ScriptedInput)
+	input.ConnectGamepad(1, "Pad")
+	input.SetGamepadAxis(0, rl.GamepadAxisLeftX, -0.5)
+
+	frame := input.ReadInput(&game)
+	if len(frame.Gamepad.Gamepads) != 2 {
+		t.Fatalf("read %d gamepads, want 2", len(frame.Gamepad.Gamepads))
+	}
+	if pad, ok := frame.Gamepad.Gamepad(0); !ok || pad.LeftStick.X != -0.5 {
+		t.Errorf("gamepad 0 is %+v", pad)
+	}
+	if pad, ok := frame.Gamepad.Gamepad(1); !ok || pad.Name != "Pad" {
+		t.Errorf("gamepad 1 is %+v", pad)
+	}
+
+	input.DisconnectGamepad(1)
+	frame = input.ReadInput(&game)
+	if _, ok := frame.Gamepad.Gamepad(1); ok {
+		t.Errorf("gamepad 1 still read after disconnecting")
+	}
+}
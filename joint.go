@@ -0,0 +1,566 @@
+package raychip
+
+import (
+	"encoding/json"
+	"fmt"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/jakecoffman/cp/v2"
+)
+
+type JointKind int
+
+const (
+	PinJoint JointKind = iota
+	SlideJoint
+	PivotJoint
+	GrooveJoint
+	DampedSpring
+	DampedRotarySpring
+	RotaryLimitJoint
+	RatchetJoint
+	GearJoint
+	SimpleMotor
+)
+
+var jointKindNames = map[JointKind]string{
+	PinJoint:           "pin",
+	SlideJoint:         "slide",
+	PivotJoint:         "pivot",
+	GrooveJoint:        "groove",
+	DampedSpring:       "damped_spring",
+	DampedRotarySpring: "damped_rotary_spring",
+	RotaryLimitJoint:   "rotary_limit",
+	RatchetJoint:       "ratchet",
+	GearJoint:          "gear",
+	SimpleMotor:        "simple_motor",
+}
+
+func (k JointKind) String() string {
+	if name, ok := jointKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("JointKind(%d)", int(k))
+}
+
+// jointParams holds the settings of every kind of joint, only the ones the
+// joint's kind uses are set. Anchors are in the local coordinates of each body.
+type jointParams struct {
+	AnchorA       Vector2  `json:"anchorA"`
+	AnchorB       Vector2  `json:"anchorB"`
+	GrooveA       Vector2  `json:"grooveA"`
+	GrooveB       Vector2  `json:"grooveB"`
+	Pivot         *Vector2 `json:"pivot,omitempty"`
+	Min           float64  `json:"min,omitempty"`
+	Max           float64  `json:"max,omitempty"`
+	RestLength    float64  `json:"restLength,omitempty"`
+	RestAngle     float64  `json:"restAngle,omitempty"`
+	Stiffness     float64  `json:"stiffness,omitempty"`
+	Damping       float64  `json:"damping,omitempty"`
+	Phase         float64  `json:"phase,omitempty"`
+	Ratchet       float64  `json:"ratchet,omitempty"`
+	Ratio         float64  `json:"ratio,omitempty"`
+	Rate          float64  `json:"rate,omitempty"`
+	MaxForce      float64  `json:"maxForce,omitempty"`
+	BreakForce    float64  `json:"breakForce,omitempty"`
+	CollideBodies bool     `json:"collideBodies"`
+}
+
+// Joint constrains the bodies of two physical entities. A nil entity attaches
+// the joint to the world instead.
+type Joint struct {
+	kind         JointKind
+	a            Entity
+	b            Entity
+	idA          uint64
+	idB          uint64
+	params       jointParams
+	color        rl.Color
+	constraint   *cp.Constraint
+	game         *Game
+	drawCallback func(*Joint)
+}
+
+func newJoint(kind JointKind, a Entity, b Entity, params jointParams) Joint {
+	params.CollideBodies = true
+	jOut := Joint{
+		kind:   kind,
+		a:      a,
+		b:      b,
+		params: params,
+		color:  rl.Gray,
+	}
+	jOut.SetDrawCallback(defaultJointDrawFunc)
+	return jOut
+}
+
+func NewPinJoint(a Entity, b Entity, anchorA Vector2, anchorB Vector2) Joint {
+	return newJoint(PinJoint, a, b, jointParams{AnchorA: anchorA, AnchorB: anchorB})
+}
+
+func NewSlideJoint(a Entity, b Entity, anchorA Vector2, anchorB Vector2, min float64, max float64) Joint {
+	return newJoint(SlideJoint, a, b, jointParams{AnchorA: anchorA, AnchorB: anchorB, Min: min, Max: max})
+}
+
+// NewPivotJoint pins the entities together at a point given in world
+// coordinates when the joint is added to the game
+func NewPivotJoint(a Entity, b Entity, pivot Vector2) Joint {
+	return newJoint(PivotJoint, a, b, jointParams{Pivot: &pivot})
+}
+
+func NewGrooveJoint(a Entity, b Entity, grooveA Vector2, grooveB Vector2, anchorB Vector2) Joint {
+	return newJoint(GrooveJoint, a, b, jointParams{GrooveA: grooveA, GrooveB: grooveB, AnchorB: anchorB})
+}
+
+func NewDampedSpring(a Entity, b Entity, anchorA Vector2, anchorB Vector2, restLength float64, stiffness float64, damping float64) Joint {
+	return newJoint(DampedSpring, a, b, jointParams{
+		AnchorA:    anchorA,
+		AnchorB:    anchorB,
+		RestLength: restLength,
+		Stiffness:  stiffness,
+		Damping:    damping,
+	})
+}
+
+func NewDampedRotarySpring(a Entity, b Entity, restAngle float64, stiffness float64, damping float64) Joint {
+	return newJoint(DampedRotarySpring, a, b, jointParams{RestAngle: restAngle, Stiffness: stiffness, Damping: damping})
+}
+
+func NewRotaryLimitJoint(a Entity, b Entity, min float64, max float64) Joint {
+	return newJoint(RotaryLimitJoint, a, b, jointParams{Min: min, Max: max})
+}
+
+func NewRatchetJoint(a Entity, b Entity, phase float64, ratchet float64) Joint {
+	return newJoint(RatchetJoint, a, b, jointParams{Phase: phase, Ratchet: ratchet})
+}
+
+func NewGearJoint(a Entity, b Entity, phase float64, ratio float64) Joint {
+	return newJoint(GearJoint, a, b, jointParams{Phase: phase, Ratio: ratio})
+}
+
+func NewSimpleMotor(a Entity, b Entity, rate float64) Joint {
+	return newJoint(SimpleMotor, a, b, jointParams{Rate: rate})
+}
+
+func (j Joint) Kind() JointKind {
+	return j.kind
+}
+
+func (j Joint) EntityA() Entity {
+	return j.a
+}
+
+func (j Joint) EntityB() Entity {
+	return j.b
+}
+
+func (j *Joint) SetColor(color rl.Color) {
+	j.color = color
+}
+
+func (j Joint) Color() rl.Color {
+	return j.color
+}
+
+// SetMaxForce limits how hard the joint can pull to correct itself
+func (j *Joint) SetMaxForce(f float64) {
+	j.params.MaxForce = f
+	if j.constraint != nil && f > 0 {
+		j.constraint.SetMaxForce(f)
+	}
+}
+
+// SetBreakForce makes the joint break, removing itself from the game, once
+// the force it applies goes over f. Zero means it never breaks.
+func (j *Joint) SetBreakForce(f float64) {
+	j.params.BreakForce = f
+}
+
+func (j Joint) BreakForce() float64 {
+	return j.params.BreakForce
+}
+
+// SetCollideBodies sets whether the two joined entities collide with each other
+func (j *Joint) SetCollideBodies(collide bool) {
+	j.params.CollideBodies = collide
+	if j.constraint != nil {
+		j.constraint.SetCollideBodies(collide)
+	}
+}
+
+// SetRate sets the speed of a simple motor in radians per second
+func (j *Joint) SetRate(rate float64) {
+	j.params.Rate = rate
+	if j.constraint != nil {
+		if motor, ok := j.constraint.Class.(*cp.SimpleMotor); ok {
+			motor.ActivateBodies()
+			motor.Rate = rate
+		}
+	}
+}
+
+// Impulse is the impulse the joint applied in the latest physics step
+func (j Joint) Impulse() float64 {
+	if j.constraint == nil {
+		return 0
+	}
+	return j.constraint.Class.GetImpulse()
+}
+
+// Force is the impulse averaged over the latest physics step
+func (j Joint) Force() float64 {
+	if j.game == nil {
+		return 0
+	}
+	return j.Impulse() / j.game.Dt()
+}
+
+func (j Joint) bodyA() *cp.Body {
+	if j.constraint == nil {
+		return nil
+	}
+	return j.body(j.a)
+}
+
+func (j Joint) bodyB() *cp.Body {
+	if j.constraint == nil {
+		return nil
+	}
+	return j.body(j.b)
+}
+
+// body is the body an added joint is attached to for one of its entities
+func (j Joint) body(entity Entity) *cp.Body {
+	if entity == nil {
+		return j.game.space.StaticBody
+	}
+	return entity.base().cpBody
+}
+
+// WorldAnchorA is where the joint attaches to entity A in world coordinates
+func (j Joint) WorldAnchorA() Vector2 {
+	if body := j.bodyA(); body != nil {
+		return Vector2FromChipmunk(body.LocalToWorld(j.params.AnchorA.ToChipmunk()))
+	}
+	return j.params.AnchorA
+}
+
+func (j Joint) WorldAnchorB() Vector2 {
+	if body := j.bodyB(); body != nil {
+		return Vector2FromChipmunk(body.LocalToWorld(j.params.AnchorB.ToChipmunk()))
+	}
+	return j.params.AnchorB
+}
+
+func (j *Joint) Draw() {
+	if j.drawCallback != nil {
+		j.drawCallback(j)
+	}
+}
+
+func defaultJointDrawFunc(j *Joint) {
+	switch j.kind {
+	case PinJoint, SlideJoint, PivotJoint, GrooveJoint, DampedSpring:
+		rendererFor(j.game).DrawLine(j.WorldAnchorA(), j.WorldAnchorB(), 2, j.color)
+	}
+}
+
+func (j Joint) DefaultDraw() {
+	defaultJointDrawFunc(&j)
+}
+
+func (j *Joint) SetDrawCallback(callback func(*Joint)) {
+	j.drawCallback = callback
+}
+
+func (j *Joint) newConstraint(a *cp.Body, b *cp.Body) *cp.Constraint {
+	p := j.params
+	switch j.kind {
+	case PinJoint:
+		return cp.NewPinJoint(a, b, p.AnchorA.ToChipmunk(), p.AnchorB.ToChipmunk())
+	case SlideJoint:
+		return cp.NewSlideJoint(a, b, p.AnchorA.ToChipmunk(), p.AnchorB.ToChipmunk(), p.Min, p.Max)
+	case PivotJoint:
+		return cp.NewPivotJoint2(a, b, p.AnchorA.ToChipmunk(), p.AnchorB.ToChipmunk())
+	case GrooveJoint:
+		return cp.NewGrooveJoint(a, b, p.GrooveA.ToChipmunk(), p.GrooveB.ToChipmunk(), p.AnchorB.ToChipmunk())
+	case DampedSpring:
+		return cp.NewDampedSpring(a, b, p.AnchorA.ToChipmunk(), p.AnchorB.ToChipmunk(), p.RestLength, p.Stiffness, p.Damping)
+	case DampedRotarySpring:
+		return cp.NewDampedRotarySpring(a, b, p.RestAngle, p.Stiffness, p.Damping)
+	case RotaryLimitJoint:
+		return cp.NewRotaryLimitJoint(a, b, p.Min, p.Max)
+	case RatchetJoint:
+		return cp.NewRatchetJoint(a, b, p.Phase, p.Ratchet)
+	case GearJoint:
+		return cp.NewGearJoint(a, b, p.Phase, p.Ratio)
+	case SimpleMotor:
+		return cp.NewSimpleMotor(a, b, p.Rate)
+	}
+	panic(fmt.Sprintf("unknown joint kind %v", j.kind))
+}
+
+// jointBody is the body a joint attaches to for an entity, a nil entity is
+// the world
+func (game *Game) jointBody(entity Entity) (*cp.Body, error) {
+	if entity == nil {
+		return game.space.StaticBody, nil
+	}
+	e := entity.base()
+	if e.game != game {
+		return nil, fmt.Errorf("joint entity %d is not in the game", e.id)
+	}
+	if e.cpBody == nil {
+		return nil, fmt.Errorf("joint entity %d has no physics body", e.id)
+	}
+	return e.cpBody, nil
+}
+
+// jointEntity finds a joint's entity, joints loaded from JSON only know the
+// ids of their entities
+func (game *Game) jointEntity(entity Entity, id uint64) (Entity, error) {
+	if entity != nil || id == 0 {
+		return entity, nil
+	}
+	entity, ok := game.EntityByID(id)
+	if !ok {
+		return nil, fmt.Errorf("joint entity %d not found", id)
+	}
+	return entity, nil
+}
+
+// AddJoint adds a joint between the bodies of its entities. It fails if an
+// entity isn't a physical entity in this game, or if an entity saved by id
+// can't be found.
+func (game *Game) AddJoint(joint *Joint) error {
+	a, err := game.jointEntity(joint.a, joint.idA)
+	if err != nil {
+		return err
+	}
+	b, err := game.jointEntity(joint.b, joint.idB)
+	if err != nil {
+		return err
+	}
+	bodyA, err := game.jointBody(a)
+	if err != nil {
+		return err
+	}
+	bodyB, err := game.jointBody(b)
+	if err != nil {
+		return err
+	}
+	joint.a, joint.b = a, b
+
+	// a pivot is given in world coordinates, keep it as a pair of anchors
+	// so the joint can be saved and re-added later
+	if joint.params.Pivot != nil {
+		pivot := joint.params.Pivot.ToChipmunk()
+		joint.params.AnchorA = Vector2FromChipmunk(bodyA.WorldToLocal(pivot))
+		joint.params.AnchorB = Vector2FromChipmunk(bodyB.WorldToLocal(pivot))
+		joint.params.Pivot = nil
+	}
+
+	constraint := joint.newConstraint(bodyA, bodyB)
+	if joint.params.MaxForce > 0 {
+		constraint.SetMaxForce(joint.params.MaxForce)
+	}
+	constraint.SetCollideBodies(joint.params.CollideBodies)
+	constraint.UserData = joint
+
+	if game.stepping {
+		game.space.AddPostStepCallback(func(space *cp.Space, key any, data any) {
+			space.AddConstraint(constraint)
+		}, constraint, nil)
+	} else {
+		game.space.AddConstraint(constraint)
+	}
+
+	joint.constraint = constraint
+	joint.game = game
+	game.joints = append(game.joints, joint)
+	return nil
+}
+
+func (game *Game) RemoveJoint(joint *Joint) {
+	found := false
+	for i, v := range game.joints {
+		if v == joint {
+			game.joints = append(game.joints[:i], game.joints[i+1:]...)
+			found = true
+			break
+		}
+	}
+	if !found {
+		return
+	}
+
+	constraint := joint.constraint
+	remove := func() {
+		if game.space.ContainsConstraint(constraint) {
+			game.space.RemoveConstraint(constraint)
+		}
+	}
+	if game.stepping {
+		game.space.AddPostStepCallback(func(*cp.Space, any, any) {
+			remove()
+		}, constraint, nil)
+	} else {
+		remove()
+	}
+	joint.constraint = nil
+	joint.game = nil
+}
+
+func (game Game) JointsCount() int {
+	return len(game.joints)
+}
+
+// removeEntityJoints drops every joint attached to the entity
+func (game *Game) removeEntityJoints(entity Entity) {
+	for _, joint := range append([]*Joint(nil), game.joints...) {
+		if joint.a == entity || joint.b == entity {
+			game.RemoveJoint(joint)
+		}
+	}
+}
+
+// JointBreak is published on "joint.break" when a joint is removed for going
+// over its break force
+type JointBreak struct {
+	Joint *Joint
+	Force float64
+}
+
+func (game *Game) breakJoints() {
+	for _, joint := range append([]*Joint(nil), game.joints...) {
+		if joint.params.BreakForce <= 0 {
+			continue
+		}
+		if force := joint.Force(); force > joint.params.BreakForce {
+			game.RemoveJoint(joint)
+			publishIfSubscribed(&game.EventBus, "joint.break", JointBreak{Joint: joint, Force: force})
+		}
+	}
+}
+
+type jointJSON struct {
+	Kind string `json:"kind"`
+	// entity ids, zero is the world
+	A uint64 `json:"a"`
+	B uint64 `json:"b"`
+	jointParams
+}
+
+func (j Joint) MarshalJSON() ([]byte, error) {
+	out := jointJSON{Kind: j.kind.String(), A: j.idA, B: j.idB, jointParams: j.params}
+	if j.a != nil {
+		out.A = j.a.Id()
+	}
+	if j.b != nil {
+		out.B = j.b.Id()
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON restores a joint saved with MarshalJSON. Its entities are
+// looked up by id when it is added to a game.
+func (j *Joint) UnmarshalJSON(data []byte) error {
+	var in jointJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	joint, err := in.joint(nil, nil)
+	if err != nil {
+		return err
+	}
+	*j = joint
+	j.idA = in.A
+	j.idB = in.B
+	return nil
+}
+
+func (in jointJSON) joint(a Entity, b Entity) (Joint, error) {
+	for kind, name := range jointKindNames {
+		if name == in.Kind {
+			joint := newJoint(kind, a, b, in.jointParams)
+			joint.params.CollideBodies = in.CollideBodies
+			return joint, nil
+		}
+	}
+	return Joint{}, fmt.Errorf("unknown joint kind %q", in.Kind)
+}
+
+// sceneJSON is how a scene's joints are saved. Entities aren't saved, so the
+// joints refer to them by their place in the scene, counting from 1 with 0
+// for the world.
+type sceneJSON struct {
+	Joints []jointJSON `json:"joints"`
+}
+
+// MarshalJSON saves the scene's joints. It fails if a joint is attached to an
+// entity that isn't in the scene.
+func (s Scene) MarshalJSON() ([]byte, error) {
+	out := sceneJSON{Joints: make([]jointJSON, 0, len(s.joints))}
+	for _, joint := range s.joints {
+		a, err := s.jointIndex(joint.a)
+		if err != nil {
+			return nil, err
+		}
+		b, err := s.jointIndex(joint.b)
+		if err != nil {
+			return nil, err
+		}
+		out.Joints = append(out.Joints, jointJSON{Kind: joint.kind.String(), A: a, B: b, jointParams: joint.params})
+	}
+	return json.Marshal(out)
+}
+
+func (s Scene) jointIndex(entity Entity) (uint64, error) {
+	if entity == nil {
+		return 0, nil
+	}
+	for i, e := range s.entities {
+		if e == entity {
+			return uint64(i + 1), nil
+		}
+	}
+	return 0, fmt.Errorf("joint entity %d is not in the scene", entity.Id())
+}
+
+// UnmarshalJSON loads joints saved with MarshalJSON onto the scene's
+// entities, which have to be added to the scene first, in the same order as
+// when it was saved
+func (s *Scene) UnmarshalJSON(data []byte) error {
+	var in sceneJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	entity := func(i uint64) (Entity, error) {
+		if i == 0 {
+			return nil, nil
+		}
+		if i > uint64(len(s.entities)) {
+			return nil, fmt.Errorf("joint entity %d is past the scene's %d entities", i, len(s.entities))
+		}
+		return s.entities[i-1], nil
+	}
+
+	var joints []*Joint
+	for _, saved := range in.Joints {
+		a, err := entity(saved.A)
+		if err != nil {
+			return err
+		}
+		b, err := entity(saved.B)
+		if err != nil {
+			return err
+		}
+		joint, err := saved.joint(a, b)
+		if err != nil {
+			return err
+		}
+		joints = append(joints, &joint)
+	}
+	s.joints = append(s.joints, joints...)
+	return nil
+}
@@ -0,0 +1,169 @@
+package raychip
+
+import (
+	"encoding/json"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func TestAddJointNeedsBodies(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	box := NewBox(100, 100, 10, 10, rl.Red)
+	game.AddEntity(&box)
+	ball := NewPhysicalCircle(200, 100, 10, 1, rl.Red)
+
+	pin := NewPinJoint(&box, nil, Vector2{}, NewVector2(100, 50))
+	if err := game.AddJoint(&pin); err == nil {
+		t.Error("joint on a non-physical entity was added")
+	}
+	pin = NewPinJoint(&ball, nil, Vector2{}, NewVector2(200, 50))
+	if err := game.AddJoint(&pin); err == nil {
+		t.Error("joint on an entity not in the game was added")
+	}
+
+	game.AddEntity(&ball)
+	if err := game.AddJoint(&pin); err != nil {
+		t.Fatalf("AddJoint: %v", err)
+	}
+	if game.JointsCount() != 1 {
+		t.Errorf("game has %d joints, want 1", game.JointsCount())
+	}
+}
+
+func TestAddJointUnknownID(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	var joint Joint
+	if err := json.Unmarshal([]byte(`{"kind": "pin", "a": 42, "b": 0}`), &joint); err != nil {
+		t.Fatal(err)
+	}
+	if err := game.AddJoint(&joint); err == nil {
+		t.Error("joint with an unknown entity id was added")
+	}
+	if game.JointsCount() != 0 {
+		t.Errorf("game has %d joints, want 0", game.JointsCount())
+	}
+}
+
+func TestJointBreaks(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	game.SetGravity(NewVector2(0, 1000))
+	light := NewPhysicalCircle(300, 200, 10, 1, rl.Red)
+	heavy := NewPhysicalCircle(500, 200, 10, 100, rl.Red)
+	game.AddEntity(&light)
+	game.AddEntity(&heavy)
+
+	// both hang from the ceiling on joints that can hold about 5 mass units
+	strong := NewPinJoint(&light, nil, Vector2{}, NewVector2(300, 100))
+	strong.SetBreakForce(5000)
+	weak := NewPinJoint(&heavy, nil, Vector2{}, NewVector2(500, 100))
+	weak.SetBreakForce(5000)
+	for _, joint := range []*Joint{&strong, &weak} {
+		if err := game.AddJoint(joint); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var broken []JointBreak
+	game.EventBus.CreateSubscription("joint.break", JointBreak{}, func(event JointBreak) {
+		broken = append(broken, event)
+	})
+
+	game.RunFrames(30)
+
+	if len(broken) != 1 || broken[0].Joint != &weak {
+		t.Fatalf("broken joints %v, want only the heavy ball's", broken)
+	}
+	if broken[0].Force <= 5000 {
+		t.Errorf("joint broke at %v, under its break force", broken[0].Force)
+	}
+	if game.JointsCount() != 1 {
+		t.Errorf("game has %d joints, want 1", game.JointsCount())
+	}
+	if heavy.Position().Y < 300 {
+		t.Errorf("heavy ball at %v didn't fall once its joint broke", heavy.Position())
+	}
+}
+
+func TestJointJSON(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	a := NewPhysicalCircle(100, 100, 10, 1, rl.Red)
+	b := NewPhysicalCircle(200, 100, 10, 1, rl.Red)
+	game.AddEntity(&a)
+	game.AddEntity(&b)
+	spring := NewDampedSpring(&a, &b, Vector2{}, NewVector2(1, 2), 100, 50, 3)
+	spring.SetBreakForce(1000)
+
+	data, err := json.Marshal(spring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded Joint
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Kind() != DampedSpring || loaded.params != spring.params {
+		t.Errorf("loaded %v %+v, saved %v %+v", loaded.Kind(), loaded.params, spring.Kind(), spring.params)
+	}
+	if err := game.AddJoint(&loaded); err != nil {
+		t.Fatalf("AddJoint: %v", err)
+	}
+	if loaded.EntityA() != &a || loaded.EntityB() != &b {
+		t.Error("loaded joint is attached to the wrong entities")
+	}
+
+	if err := json.Unmarshal([]byte(`{"kind": "rope"}`), &loaded); err == nil {
+		t.Error("unknown joint kind loaded")
+	}
+}
+
+func TestSceneJSON(t *testing.T) {
+	newScene := func() (Scene, *Circle, *Circle) {
+		scene := NewScene()
+		a := NewPhysicalCircle(100, 100, 10, 1, rl.Red)
+		b := NewPhysicalCircle(200, 100, 10, 1, rl.Red)
+		scene.AddEntity(&a)
+		scene.AddEntity(&b)
+		return scene, &a, &b
+	}
+
+	scene, a, b := newScene()
+	pin := NewPinJoint(a, b, Vector2{}, Vector2{})
+	scene.AddJoint(&pin)
+	motor := NewSimpleMotor(b, nil, 2)
+	scene.AddJoint(&motor)
+	data, err := json.Marshal(scene)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, a, b := newScene()
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	joints := loaded.Joints()
+	if len(joints) != 2 {
+		t.Fatalf("loaded %d joints, want 2", len(joints))
+	}
+	if joints[0].Kind() != PinJoint || joints[0].EntityA() != a || joints[0].EntityB() != b {
+		t.Errorf("pin joint loaded as %v between %v and %v", joints[0].Kind(), joints[0].EntityA(), joints[0].EntityB())
+	}
+	if joints[1].Kind() != SimpleMotor || joints[1].EntityA() != b || joints[1].EntityB() != nil {
+		t.Errorf("motor loaded as %v between %v and %v", joints[1].Kind(), joints[1].EntityA(), joints[1].EntityB())
+	}
+
+	game := NewHeadlessGame(800, 600, 60)
+	if err := game.SetScene(loaded); err != nil {
+		t.Fatalf("SetScene: %v", err)
+	}
+	if game.JointsCount() != 2 {
+		t.Errorf("game has %d joints, want 2", game.JointsCount())
+	}
+
+	// joints to entities outside the scene can't be saved
+	stray := NewPhysicalCircle(300, 100, 10, 1, rl.Red)
+	bad := NewPinJoint(a, &stray, Vector2{}, Vector2{})
+	loaded.AddJoint(&bad)
+	if _, err := json.Marshal(loaded); err == nil {
+		t.Error("scene with a joint to an entity outside it was saved")
+	}
+}
@@ -0,0 +1,180 @@
+package raychip
+
+import (
+	"cmp"
+	"slices"
+)
+
+// the layers every game starts with, drawn in this order
+const (
+	LayerBackground = "background"
+	LayerWorld      = "world"
+	LayerForeground = "foreground"
+	LayerUI         = "ui"
+)
+
+type renderLayer struct {
+	name    string
+	visible bool
+	// camera overrides the game's camera when set
+	camera      *Camera
+	screenSpace bool
+}
+
+func defaultLayers() []*renderLayer {
+	return []*renderLayer{
+		{name: LayerBackground, visible: true},
+		{name: LayerWorld, visible: true},
+		{name: LayerForeground, visible: true},
+		{name: LayerUI, visible: true, screenSpace: true},
+	}
+}
+
+// SetLayer sets which render layer the entity is drawn in, LayerWorld by
+// default. Layers that don't exist yet are added on top of the others.
+func (e *EntityBase) SetLayer(name string) {
+	e.layer = name
+	if e.game != nil {
+		e.game.layer(name)
+	}
+}
+
+func (e EntityBase) Layer() string {
+	if e.layer == "" {
+		return LayerWorld
+	}
+	return e.layer
+}
+
+// SetZ orders the entity within its layer, higher z is drawn on top.
+// Entities with the same z are drawn in the order they were added.
+func (e *EntityBase) SetZ(z float64) {
+	e.z = z
+}
+
+func (e EntityBase) Z() float64 {
+	return e.z
+}
+
+// mousePosition is where the mouse is in the coordinates the entity is
+// drawn in
+func (e *EntityBase) mousePosition(game *Game) Vector2 {
+	layer := game.findLayer(e.Layer())
+	switch {
+	case layer == nil:
+		return game.mousePosition
+	case layer.screenSpace:
+		return game.mouseScreenPos
+	case layer.camera != nil:
+		return layer.camera.ScreenToWorld(game.mouseScreenPos)
+	}
+	return game.mousePosition
+}
+
+func (game Game) findLayer(name string) *renderLayer {
+	for _, layer := range game.layers {
+		if layer.name == name {
+			return layer
+		}
+	}
+	return nil
+}
+
+// layer finds a layer by name, adding it on top if it doesn't exist
+func (game *Game) layer(name string) *renderLayer {
+	if layer := game.findLayer(name); layer != nil {
+		return layer
+	}
+	layer := &renderLayer{name: name, visible: true}
+	game.layers = append(game.layers, layer)
+	return layer
+}
+
+// AddLayer adds a layer on top of all the others
+func (game *Game) AddLayer(name string) {
+	game.layer(name)
+}
+
+// AddLayerBelow adds a layer just below another one, or on top if other
+// doesn't exist. It does nothing if the layer is already there.
+func (game *Game) AddLayerBelow(name string, other string) {
+	if game.findLayer(name) != nil {
+		return
+	}
+	i := slices.IndexFunc(game.layers, func(l *renderLayer) bool {
+		return l.name == other
+	})
+	if i < 0 {
+		i = len(game.layers)
+	}
+	game.layers = slices.Insert(game.layers, i, &renderLayer{name: name, visible: true})
+}
+
+// Layers are the names of the game's layers from the bottom up
+func (game Game) Layers() []string {
+	names := make([]string, len(game.layers))
+	for i, layer := range game.layers {
+		names[i] = layer.name
+	}
+	return names
+}
+
+func (game *Game) SetLayerVisible(name string, visible bool) {
+	game.layer(name).visible = visible
+}
+
+func (game Game) IsLayerVisible(name string) bool {
+	layer := game.findLayer(name)
+	return layer != nil && layer.visible
+}
+
+// SetLayerCamera draws a layer with its own camera rather than the game's,
+// e.g. for parallax backgrounds. The camera is updated every frame like the
+// game's, and nil goes back to the game's camera.
+func (game *Game) SetLayerCamera(name string, camera *Camera) {
+	game.layer(name).camera = camera
+}
+
+// SetLayerScreenSpace draws a layer without any camera so positions are in
+// screen coordinates. LayerUI is drawn in screen space by default.
+func (game *Game) SetLayerScreenSpace(name string, screenSpace bool) {
+	game.layer(name).screenSpace = screenSpace
+}
+
+func (game Game) IsLayerScreenSpace(name string) bool {
+	layer := game.findLayer(name)
+	return layer != nil && layer.screenSpace
+}
+
+// updateLayerCameras updates each layer camera once, skipping the game's own
+// camera which is updated separately
+func (game *Game) updateLayerCameras() {
+	var updated []*Camera
+	for _, layer := range game.layers {
+		if layer.camera == nil || layer.camera == &game.camera || slices.Contains(updated, layer.camera) {
+			continue
+		}
+		layer.camera.update(game.frameTime)
+		updated = append(updated, layer.camera)
+	}
+}
+
+// drawOrder is the game's entities sorted by layer then z, keeping the order
+// they were added otherwise
+func (game Game) drawOrder() [][]Entity {
+	index := make(map[string]int, len(game.layers))
+	for i, layer := range game.layers {
+		index[layer.name] = i
+	}
+	byLayer := make([][]Entity, len(game.layers))
+	for _, entity := range game.entities {
+		i := index[entity.base().Layer()]
+		byLayer[i] = append(byLayer[i], entity)
+	}
+	for _, entities := range byLayer {
+		slices.SortStableFunc(entities, func(a Entity, b Entity) int {
+			return cmp.Compare(a.base().z, b.base().z)
+		})
+	}
+	return byLayer
+}
@@ -0,0 +1,80 @@
+package raychip
+
+import (
+	"fmt"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func TestDrawOrder(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	names := make(map[Entity]string)
+	add := func(name string, layer string, z float64) {
+		ball := NewCircle(0, 0, 10, rl.Red)
+		if layer != "" {
+			ball.SetLayer(layer)
+		}
+		ball.SetZ(z)
+		game.AddEntity(&ball)
+		names[&ball] = name
+	}
+	add("ui", LayerUI, -5)
+	add("a", "", 1)
+	add("b", "", 0)
+	add("c", "", 1)
+	add("background", LayerBackground, 10)
+	add("d", "", 0)
+	add("top", "top", 0)
+	game.AddLayerBelow("below world", LayerWorld)
+	add("e", "below world", 0)
+
+	var got [][]string
+	for _, entities := range game.drawOrder() {
+		var layer []string
+		for _, entity := range entities {
+			layer = append(layer, names[entity])
+		}
+		got = append(got, layer)
+	}
+
+	// z orders within a layer, ties are drawn in the order they were added
+	want := "[[background] [e] [b d a c] [] [ui] [top]]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("draw order is %v, want %v", got, want)
+	}
+	if layers := fmt.Sprint(game.Layers()); layers != "[background below world world foreground ui top]" {
+		t.Errorf("layers are %v", layers)
+	}
+}
+
+func TestLayerMousePosition(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	game.EnableMouseInput()
+	game.Camera().SetTarget(NewVector2(1400, 300))
+	parallax := NewCamera(800, 600)
+	parallax.SetTarget(NewVector2(700, 300))
+	game.SetLayerCamera(LayerBackground, &parallax)
+
+	button := NewBox(100, 100, 50, 50, rl.Gray)
+	button.SetLayer(LayerUI)
+	world := NewCircle(1100, 100, 20, rl.Red)
+	hill := NewCircle(400, 100, 20, rl.Green)
+	hill.SetLayer(LayerBackground)
+	for _, entity := range []Entity{&button, &world, &hill} {
+		game.AddEntity(entity)
+	}
+	clicked := map[string]int{}
+	button.OnClick(&game, rl.MouseButtonLeft, MousePressed, func() { clicked["button"]++ })
+	world.OnClick(&game, rl.MouseButtonLeft, MousePressed, func() { clicked["world"]++ })
+	hill.OnClick(&game, rl.MouseButtonLeft, MousePressed, func() { clicked["hill"]++ })
+
+	// every entity is at screen 100,100 in its own layer
+	input := game.InputSource().(*ScriptedInput)
+	input.ClickAt(100, 100, rl.MouseButtonLeft)
+	game.RunFrames(2)
+
+	if fmt.Sprint(clicked) != "map[button:1 hill:1 world:1]" {
+		t.Errorf("clicked %v, want each once", clicked)
+	}
+}
@@ -0,0 +1,483 @@
+package raychip
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/jakecoffman/cp/v2"
+)
+
+type Polygon struct {
+	EntityBase
+	// outline and its convex pieces, relative to the polygon's centroid
+	vertices       []Vector2
+	pieces         [][]Vector2
+	updateCallback func(*Polygon)
+	drawCallback   func(*Polygon)
+}
+
+var (
+	// ErrDegeneratePolygon is returned for outlines with fewer than three
+	// vertices or no area
+	ErrDegeneratePolygon       = errors.New("polygon needs at least 3 vertices and some area")
+	ErrSelfIntersectingPolygon = errors.New("polygon outline intersects itself")
+)
+
+// NewPolygon makes a polygon from an outline given relative to (x, y). The
+// outline may be concave, it is split into convex pieces for chipmunk, but it
+// must not cross itself. The polygon's position is the centroid of the
+// outline.
+func NewPolygon(x float64, y float64, vertices []Vector2, color rl.Color) (Polygon, error) {
+	centroid, pieces, outline, err := preparePolygon(vertices)
+	if err != nil {
+		return Polygon{}, err
+	}
+	pOut := Polygon{
+		EntityBase: EntityBase{
+			position: NewVector2(x+centroid.X, y+centroid.Y),
+			color:    color,
+			physical: false,
+		},
+		vertices: outline,
+		pieces:   pieces,
+	}
+	pOut.SetDrawCallback(defaultPolygonDrawFunc)
+	return pOut, nil
+}
+
+func NewPhysicalPolygon(x float64, y float64, vertices []Vector2, mass float64, color rl.Color) (Polygon, error) {
+	centroid, pieces, outline, err := preparePolygon(vertices)
+	if err != nil {
+		return Polygon{}, err
+	}
+	pOut := Polygon{
+		EntityBase: EntityBase{
+			position:    NewVector2(x+centroid.X, y+centroid.Y),
+			mass:        mass,
+			color:       color,
+			physical:    true,
+			elasticity:  1.0,
+			friction:    1.0,
+			velocityMax: 800.0,
+		},
+		vertices: outline,
+		pieces:   pieces,
+	}
+	pOut.SetDrawCallback(defaultPolygonDrawFunc)
+	return pOut, nil
+}
+
+// preparePolygon checks the outline, winds it counter-clockwise, splits it
+// into convex pieces and moves everything so the centroid is at the origin
+func preparePolygon(vertices []Vector2) (Vector2, [][]Vector2, []Vector2, error) {
+	if len(vertices) < 3 {
+		return Vector2{}, nil, nil, ErrDegeneratePolygon
+	}
+	if selfIntersects(vertices) {
+		return Vector2{}, nil, nil, ErrSelfIntersectingPolygon
+	}
+	// zero area would give a NaN centroid and moment of inertia
+	if signedArea(vertices) == 0 {
+		return Vector2{}, nil, nil, ErrDegeneratePolygon
+	}
+
+	outline := append([]Vector2(nil), vertices...)
+	if signedArea(outline) < 0 {
+		for i, j := 0, len(outline)-1; i < j; i, j = i+1, j-1 {
+			outline[i], outline[j] = outline[j], outline[i]
+		}
+	}
+
+	centroid := polygonCentroid(outline)
+	for i := range outline {
+		outline[i] = NewVector2(outline[i].X-centroid.X, outline[i].Y-centroid.Y)
+	}
+
+	pieces, err := decomposePolygon(outline)
+	if err != nil {
+		return Vector2{}, nil, nil, err
+	}
+	return centroid, pieces, outline, nil
+}
+
+func (p *Polygon) limitVelocity(body *cp.Body, gravity cp.Vector, damping float64, dt float64) {
+	maxSpeed := p.velocityMax // Maximum speed (pixels/second)
+	cp.BodyUpdateVelocity(body, gravity, damping, dt)
+	velocity := body.Velocity()
+	speed := math.Sqrt(velocity.X*velocity.X + velocity.Y*velocity.Y)
+	if speed > maxSpeed {
+		scale := maxSpeed / speed
+		body.SetVelocity(velocity.X*scale, velocity.Y*scale)
+	}
+}
+
+func (e *Polygon) addToGame(game *Game, args ...any) {
+	if e.physical || e.sensor {
+		if body, ok := args[0].(*cp.Body); ok {
+			if shape, ok := args[1].(*cp.Shape); ok {
+				game.physical = true
+				if e.physical {
+					body = game.space.AddBody(cp.NewBody(e.mass, e.moment()))
+					body.SetVelocityUpdateFunc(e.limitVelocity)
+				} else {
+					// non-physical sensors only move when told to
+					body = game.space.AddBody(newSensorBody())
+				}
+				body.SetPosition(cp.Vector{X: e.position.X, Y: e.position.Y})
+				body.SetAngle(e.angle)
+				body.SetVelocity(e.velocity.X, e.velocity.Y)
+				e.cpBody = body
+				e.extraShapes = nil
+				for i, piece := range e.pieces {
+					verts := make([]cp.Vector, len(piece))
+					for j, v := range piece {
+						verts[j] = v.ToChipmunk()
+					}
+					shape = game.space.AddShape(cp.NewPolyShape(body, len(verts), verts, cp.NewTransformIdentity(), 0))
+					shape.SetElasticity(e.elasticity)
+					shape.SetFriction(e.friction)
+					if i == 0 {
+						e.cpShape = shape
+					} else {
+						e.extraShapes = append(e.extraShapes, shape)
+					}
+				}
+			}
+		}
+	}
+	game.registerEntity(e)
+}
+
+// moment adds up the moment of inertia of each piece about the centroid,
+// sharing the mass out by area
+func (p *Polygon) moment() float64 {
+	totalArea := math.Abs(signedArea(p.vertices))
+	var moment float64
+	for _, piece := range p.pieces {
+		verts := make([]cp.Vector, len(piece))
+		for i, v := range piece {
+			verts[i] = v.ToChipmunk()
+		}
+		mass := p.mass * math.Abs(signedArea(piece)) / totalArea
+		moment += cp.MomentForPoly(mass, len(verts), verts, cp.Vector{}, 0)
+	}
+	return moment
+}
+
+// Vertices is the outline of the polygon relative to its position
+func (p Polygon) Vertices() []Vector2 {
+	return p.vertices
+}
+
+// Pieces are the convex polygons the outline was split into
+func (p Polygon) Pieces() [][]Vector2 {
+	return p.pieces
+}
+
+// WorldVertices is the outline of the polygon moved to its current position and angle
+func (p *Polygon) WorldVertices() []Vector2 {
+	return transformVertices(p.vertices, p.Position(), p.Angle())
+}
+
+func transformVertices(vertices []Vector2, pos Vector2, angle float64) []Vector2 {
+	sin, cos := math.Sincos(angle)
+	out := make([]Vector2, len(vertices))
+	for i, v := range vertices {
+		out[i] = NewVector2(pos.X+v.X*cos-v.Y*sin, pos.Y+v.X*sin+v.Y*cos)
+	}
+	return out
+}
+
+func defaultPolygonDrawFunc(p *Polygon) {
+	pos := p.RenderPosition()
+	angle := p.RenderAngle()
+	for _, piece := range p.pieces {
+		p.renderer().DrawPolygon(transformVertices(piece, pos, angle), p.color)
+	}
+}
+
+func (p Polygon) DefaultDraw() {
+	defaultPolygonDrawFunc(&p)
+}
+
+func (p *Polygon) Update() {
+	if p.updateCallback != nil {
+		p.updateCallback(p)
+	}
+}
+
+func (p *Polygon) Draw() {
+	if p.drawCallback != nil {
+		p.drawCallback(p)
+	}
+}
+
+func (p *Polygon) SetDrawCallback(callback func(*Polygon)) {
+	p.drawCallback = callback
+}
+
+func (p *Polygon) SetUpdateCallback(callback func(*Polygon)) {
+	var oldUpdateCallback func(*Polygon)
+	if p.updateCallback != nil {
+		oldUpdateCallback = p.updateCallback
+	}
+
+	p.updateCallback = func(p *Polygon) {
+		if oldUpdateCallback != nil {
+			oldUpdateCallback(p)
+		}
+		callback(p)
+	}
+}
+
+func (p *Polygon) OnClick(game *Game, button rl.MouseButton, state MouseState, callback func()) SubscriptionID {
+	return p.onClick(game, button, state, p.CheckMouseCollision, callback)
+}
+
+func (p *Polygon) CheckMouseCollision(mousePos Vector2) bool {
+	// move the point into the polygon's frame rather than moving the polygon
+	pos := p.Position()
+	sin, cos := math.Sincos(-p.Angle())
+	dx, dy := mousePos.X-pos.X, mousePos.Y-pos.Y
+	return pointInPolygon(NewVector2(dx*cos-dy*sin, dx*sin+dy*cos), p.vertices)
+}
+
+func (p *Polygon) SetTexture(texture rl.Texture2D) {
+	p.SetDrawCallback(func(p *Polygon) {
+		p.drawTexture(texture)
+	})
+}
+
+// SetSprite draws the polygon as a sprite, which doesn't need to be added to
+// the game itself
+func (p *Polygon) SetSprite(sprite *Sprite) {
+	p.SetDrawCallback(func(p *Polygon) {
+		sprite.drawAt(p.renderer(), p.RenderPosition(), p.RenderAngle()+sprite.Angle())
+	})
+}
+
+// ConvexHull returns the smallest convex polygon containing all the points,
+// wound the same way chipmunk winds its polygons
+func ConvexHull(points []Vector2) []Vector2 {
+	verts := make([]cp.Vector, len(points))
+	for i, p := range points {
+		verts[i] = p.ToChipmunk()
+	}
+	count := cp.ConvexHull(len(verts), verts, nil, 0)
+	hull := make([]Vector2, count)
+	for i := range hull {
+		hull[i] = Vector2FromChipmunk(verts[i])
+	}
+	return hull
+}
+
+func cross(o Vector2, a Vector2, b Vector2) float64 {
+	return (a.X-o.X)*(b.Y-o.Y) - (a.Y-o.Y)*(b.X-o.X)
+}
+
+func signedArea(vertices []Vector2) float64 {
+	var area float64
+	for i, v := range vertices {
+		next := vertices[(i+1)%len(vertices)]
+		area += v.X*next.Y - next.X*v.Y
+	}
+	return area / 2
+}
+
+func polygonCentroid(vertices []Vector2) Vector2 {
+	area := signedArea(vertices)
+	if area == 0 {
+		// degenerate, fall back on the average of the points
+		var sum Vector2
+		for _, v := range vertices {
+			sum.X += v.X
+			sum.Y += v.Y
+		}
+		n := float64(len(vertices))
+		return NewVector2(sum.X/n, sum.Y/n)
+	}
+	var cx, cy float64
+	for i, v := range vertices {
+		next := vertices[(i+1)%len(vertices)]
+		c := v.X*next.Y - next.X*v.Y
+		cx += (v.X + next.X) * c
+		cy += (v.Y + next.Y) * c
+	}
+	return NewVector2(cx/(6*area), cy/(6*area))
+}
+
+func pointInPolygon(point Vector2, vertices []Vector2) bool {
+	inside := false
+	for i, j := 0, len(vertices)-1; i < len(vertices); j, i = i, i+1 {
+		a, b := vertices[i], vertices[j]
+		if (a.Y > point.Y) != (b.Y > point.Y) &&
+			point.X < (b.X-a.X)*(point.Y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+func pointInTriangle(p Vector2, a Vector2, b Vector2, c Vector2) bool {
+	return cross(a, b, p) >= 0 && cross(b, c, p) >= 0 && cross(c, a, p) >= 0
+}
+
+func isConvex(vertices []Vector2) bool {
+	n := len(vertices)
+	for i := range vertices {
+		if cross(vertices[i], vertices[(i+1)%n], vertices[(i+2)%n]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// decomposePolygon splits a counter-clockwise outline into convex pieces by
+// ear clipping it into triangles then merging neighbouring triangles back
+// together wherever the result stays convex (Hertel-Mehlhorn)
+func decomposePolygon(outline []Vector2) ([][]Vector2, error) {
+	if len(outline) <= 3 || isConvex(outline) {
+		return [][]Vector2{outline}, nil
+	}
+
+	pieces, err := triangulate(outline)
+	if err != nil {
+		return nil, err
+	}
+	for merged := true; merged; {
+		merged = false
+		for i := 0; i < len(pieces) && !merged; i++ {
+			for j := i + 1; j < len(pieces) && !merged; j++ {
+				joined, ok := joinPieces(pieces[i], pieces[j])
+				if ok && isConvex(indexVertices(outline, joined)) {
+					pieces[i] = joined
+					pieces = append(pieces[:j], pieces[j+1:]...)
+					merged = true
+				}
+			}
+		}
+	}
+
+	out := make([][]Vector2, len(pieces))
+	for i, piece := range pieces {
+		out[i] = indexVertices(outline, piece)
+	}
+	return out, nil
+}
+
+func indexVertices(vertices []Vector2, indices []int) []Vector2 {
+	out := make([]Vector2, len(indices))
+	for i, index := range indices {
+		out[i] = vertices[index]
+	}
+	return out
+}
+
+// triangulate ear clips the outline, returning triangles as indices into it
+func triangulate(outline []Vector2) ([][]int, error) {
+	remaining := make([]int, len(outline))
+	for i := range remaining {
+		remaining[i] = i
+	}
+
+	var triangles [][]int
+	for len(remaining) > 3 {
+		n := len(remaining)
+		clipped := false
+		for i := 0; i < n; i++ {
+			prev, cur, next := remaining[(i+n-1)%n], remaining[i], remaining[(i+1)%n]
+			turn := cross(outline[prev], outline[cur], outline[next])
+			if turn == 0 {
+				// collinear points add nothing, just drop them
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				clipped = true
+				break
+			}
+			if turn < 0 {
+				continue
+			}
+			ear := true
+			for _, other := range remaining {
+				if other == prev || other == cur || other == next {
+					continue
+				}
+				if pointInTriangle(outline[other], outline[prev], outline[cur], outline[next]) {
+					ear = false
+					break
+				}
+			}
+			if ear {
+				triangles = append(triangles, []int{prev, cur, next})
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				clipped = true
+				break
+			}
+		}
+		if !clipped {
+			// only a self intersecting outline runs out of ears
+			return nil, fmt.Errorf("%w, %d vertices left unclipped", ErrSelfIntersectingPolygon, len(remaining))
+		}
+	}
+	if len(remaining) == 3 {
+		triangles = append(triangles, remaining)
+	}
+	return triangles, nil
+}
+
+// selfIntersects checks every pair of edges that aren't neighbours
+func selfIntersects(outline []Vector2) bool {
+	n := len(outline)
+	for i := 0; i < n; i++ {
+		a, b := outline[i], outline[(i+1)%n]
+		for j := i + 2; j < n; j++ {
+			if i == 0 && j == n-1 {
+				continue
+			}
+			if segmentsIntersect(a, b, outline[j], outline[(j+1)%n]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// segmentsIntersect includes segments that only touch
+func segmentsIntersect(p1 Vector2, p2 Vector2, q1 Vector2, q2 Vector2) bool {
+	d1, d2 := cross(q1, q2, p1), cross(q1, q2, p2)
+	d3, d4 := cross(p1, p2, q1), cross(p1, p2, q2)
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	onSegment := func(a Vector2, b Vector2, p Vector2) bool {
+		return math.Min(a.X, b.X) <= p.X && p.X <= math.Max(a.X, b.X) &&
+			math.Min(a.Y, b.Y) <= p.Y && p.Y <= math.Max(a.Y, b.Y)
+	}
+	return (d1 == 0 && onSegment(q1, q2, p1)) ||
+		(d2 == 0 && onSegment(q1, q2, p2)) ||
+		(d3 == 0 && onSegment(p1, p2, q1)) ||
+		(d4 == 0 && onSegment(p1, p2, q2))
+}
+
+// joinPieces merges two pieces that share an edge into one outline
+func joinPieces(a []int, b []int) ([]int, bool) {
+	for i := range a {
+		a0, a1 := a[i], a[(i+1)%len(a)]
+		for j := range b {
+			if b[j] != a1 || b[(j+1)%len(b)] != a0 {
+				continue
+			}
+			// all of a from a1 round to a0, then the rest of b
+			out := make([]int, 0, len(a)+len(b)-2)
+			for k := 0; k < len(a); k++ {
+				out = append(out, a[(i+1+k)%len(a)])
+			}
+			for k := 2; k < len(b); k++ {
+				out = append(out, b[(j+k)%len(b)])
+			}
+			return out, true
+		}
+	}
+	return nil, false
+}
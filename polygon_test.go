@@ -0,0 +1,49 @@
+package raychip
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func TestPolygonRejectsBadOutlines(t *testing.T) {
+	tests := []struct {
+		name     string
+		vertices []Vector2
+		want     error
+	}{
+		{"nil", nil, ErrDegeneratePolygon},
+		{"two vertices", []Vector2{{0, 0}, {10, 0}}, ErrDegeneratePolygon},
+		{"zero area", []Vector2{{0, 0}, {10, 0}, {20, 0}}, ErrDegeneratePolygon},
+		{"bowtie", []Vector2{{0, 0}, {10, 10}, {10, 0}, {0, 10}}, ErrSelfIntersectingPolygon},
+		{"pentagram", []Vector2{{0, -10}, {6, 8}, {-9, -3}, {9, -3}, {-6, 8}}, ErrSelfIntersectingPolygon},
+	}
+	for _, test := range tests {
+		if _, err := NewPhysicalPolygon(100, 100, test.vertices, 1, rl.Red); !errors.Is(err, test.want) {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestConcavePolygonFalls(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	game.SetGravity(NewVector2(0, 1000))
+	l := []Vector2{{0, 0}, {30, 0}, {30, 10}, {10, 10}, {10, 30}, {0, 30}}
+	poly, err := NewPhysicalPolygon(100, 100, l, 1, rl.Red)
+	if err != nil {
+		t.Fatalf("NewPhysicalPolygon: %v", err)
+	}
+	if len(poly.Pieces()) < 2 {
+		t.Errorf("concave outline split into %d pieces", len(poly.Pieces()))
+	}
+	game.AddEntity(&poly)
+
+	game.RunFrames(10)
+
+	pos := poly.Position()
+	if math.IsNaN(pos.X) || math.IsNaN(pos.Y) || pos.Y <= 100 {
+		t.Errorf("polygon ended up at %v", pos)
+	}
+}
@@ -1,6 +1,9 @@
 package raychip
 
 import (
+	"errors"
+	"math"
+
 	rl "github.com/gen2brain/raylib-go/raylib"
 	"github.com/jakecoffman/cp/v2"
 )
@@ -13,31 +16,94 @@ type Game struct {
 	physical        bool
 	space           *cp.Space
 	entities        []Entity
+	registry        map[uint64]Entity
+	joints          []*Joint
+	nextID          uint64
 	backgroundColor rl.Color
 	updateCallback  func(*Game)
 	drawCallback    func(*Game)
 	mousePosition   Vector2
+	mouseScreenPos  Vector2
+	camera          Camera
+	layers          []*renderLayer
 	EventBus        EventBus
 	inputs          GameInputs
+	inputMap        InputMap
+	input           InputFrame
+	inputSource     InputSource
+	renderer        Renderer
+	recorder        *inputRecorder
+	replay          []InputFrame
+	replayIndex     int
+	headless        bool
+	closed          bool
+	stepping        bool
+
+	// entities taken out by ClearEntities, which keep their subscriptions
+	// until they are added back or removed for good with RemoveEntity
+	detached map[uint64]Entity
+
+	collisionHandler *cp.CollisionHandler
+	collisionLayers  map[string]uint
+
+	// fixed timestep physics
+	physicsStep float64
+	maxSubsteps int
+	accumulator float64
+	alpha       float64
+	frameTime   float64
 }
 
-func NewGame(screenWidth int32, screenHeight int32, targetFPS int32) Game {
+func newGame(screenWidth int32, screenHeight int32, targetFPS int32) Game {
 	space := cp.NewSpace()
 	game := Game{
 		screenWidth:     screenWidth,
 		screenHeight:    screenHeight,
 		targetFPS:       targetFPS,
+		physicsStep:     1.0 / float64(targetFPS),
+		maxSubsteps:     5,
+		frameTime:       1.0 / float64(targetFPS),
 		physical:        false,
 		windowName:      "Game",
 		backgroundColor: rl.RayWhite,
 		space:           space,
-		EventBus:        NewEventBus(),
+		registry:        make(map[uint64]Entity),
+		detached:        make(map[uint64]Entity),
+		camera:          NewCamera(screenWidth, screenHeight),
+		layers:          defaultLayers(),
+		inputs: GameInputs{
+			stickDeadzone:   0.15,
+			triggerDeadzone: 0.05,
+		},
+		EventBus: NewEventBus(),
 	}
+	game.installCollisionHandler()
+	// the game loop runs callbacks, anything published elsewhere is queued
+	// for it
+	game.EventBus.setOwner(goroutineID())
+	return game
+}
+
+func NewGame(screenWidth int32, screenHeight int32, targetFPS int32) Game {
+	game := newGame(screenWidth, screenHeight, targetFPS)
+	game.inputSource = RaylibInput{}
+	game.renderer = RaylibRenderer{}
 	rl.InitWindow(game.screenWidth, game.screenHeight, game.windowName)
 	rl.SetTargetFPS(game.targetFPS)
 	return game
 }
 
+// NewHeadlessGame creates a game that never opens a window. Physics, entity
+// updates and the EventBus run as usual, input comes from a ScriptedInput
+// rather than raylib and nothing is drawn unless a renderer is set with
+// SetRenderer, so it can be driven with RunFrames from tests.
+func NewHeadlessGame(screenWidth int32, screenHeight int32, targetFPS int32) Game {
+	game := newGame(screenWidth, screenHeight, targetFPS)
+	game.headless = true
+	game.inputSource = NewScriptedInput()
+	return game
+}
+
 type MouseState int
 
 const (
@@ -51,6 +117,10 @@ func (game *Game) SetWindowName(name string) {
 	game.windowName = name
 }
 
+func (game Game) IsHeadless() bool {
+	return game.headless
+}
+
 func (game Game) EntitiesCount() int {
 	return len(game.entities)
 }
@@ -70,18 +140,52 @@ func (game *Game) AddPerimiterWall(width float64, color rl.Color) {
 	game.AddEntity(&wallLeft)
 }
 
+// Dt is the fixed physics timestep, 1/targetFPS unless set with SetPhysicsStep
 func (game Game) Dt() float64 {
-	return 1.0 / float64(game.targetFPS)
+	return game.physicsStep
+}
+
+func (game *Game) SetPhysicsStep(dt float64) {
+	if dt > 0 {
+		game.physicsStep = dt
+	}
+}
+
+// SetMaxSubsteps caps the number of physics steps taken in a single frame.
+// Any time beyond that is dropped so a slow frame can't snowball.
+func (game *Game) SetMaxSubsteps(n int) {
+	if n > 0 {
+		game.maxSubsteps = n
+	}
+}
+
+// Alpha is how far between the last two physics steps the current frame is,
+// from 0 to 1. Draw functions use it to interpolate entity positions.
+func (game Game) Alpha() float64 {
+	return game.alpha
+}
+
+// FrameTime is the real time in seconds the last frame took
+func (game Game) FrameTime() float64 {
+	return game.frameTime
 }
 
 func (game *Game) SetBackgroundColor(color rl.Color) {
 	game.backgroundColor = color
 }
 
+// MousePosition is where the mouse is in the world, taking the camera into account
 func (game Game) MousePosition() Vector2 {
 	return game.mousePosition
 }
 
+func (game Game) MouseScreenPosition() Vector2 {
+	return game.mouseScreenPos
+}
+
+// SetDrawCallback sets a callback run after the entities are drawn. It draws
+// in screen coordinates, outside the camera, so it suits HUDs; use
+// game.WorldToScreen to draw at a point in the world.
 func (game *Game) SetDrawCallback(callback func(*Game)) {
 	game.drawCallback = callback
 }
@@ -111,21 +215,23 @@ func (game *Game) OnClick(button rl.MouseButton, state MouseState, callback func
 			oldUpdateCallback(g)
 		}
 
+		// use the frame's input rather than raylib so replays click too
+		mouse := g.input.Mouse
 		switch state {
 		case MouseUp:
-			if rl.IsMouseButtonUp(button) {
+			if mouse.IsButtonUp(button) {
 				callback()
 			}
 		case MouseDown:
-			if rl.IsMouseButtonDown(button) {
+			if mouse.IsButtonDown(button) {
 				callback()
 			}
 		case MousePressed:
-			if rl.IsMouseButtonPressed(button) {
+			if mouse.IsButtonPressed(button) {
 				callback()
 			}
 		case MouseReleased:
-			if rl.IsMouseButtonReleased(button) {
+			if mouse.IsButtonReleased(button) {
 				callback()
 			}
 		}
@@ -145,88 +251,300 @@ func (game *Game) SetDamping(d float64) {
 }
 
 func (game *Game) Update() {
+	// the loop may have moved to another goroutine since the game was made
+	game.EventBus.setOwner(goroutineID())
 
-	// step the physics forward if enabled
+	// step the physics forward at a fixed rate if enabled
 	if game.physical {
-		game.space.Step(game.Dt())
+		game.collisionHandler.UserData = game
+		game.accumulator += game.frameTime
+		steps := 0
+		for game.accumulator >= game.physicsStep {
+			if steps == game.maxSubsteps {
+				game.accumulator = math.Mod(game.accumulator, game.physicsStep)
+				break
+			}
+			for _, entity := range game.entities {
+				entity.base().savePhysicsState()
+			}
+			game.stepping = true
+			game.space.Step(game.physicsStep)
+			game.stepping = false
+			game.breakJoints()
+			game.accumulator -= game.physicsStep
+			steps++
+		}
+		game.alpha = game.accumulator / game.physicsStep
+	}
+
+	// Update all game entites. Callbacks may add or remove entities so work
+	// from a copy and skip anything removed along the way.
+	entities := append([]Entity(nil), game.entities...)
+	for _, entity := range entities {
+		if registered, ok := game.registry[entity.Id()]; ok && registered == entity {
+			entity.Update()
+		}
+	}
+
+	// animations run on the frame time rather than the physics step
+	for _, entity := range entities {
+		if registered, ok := game.registry[entity.Id()]; ok && registered == entity {
+			if animator := entity.base().animator; animator != nil {
+				animator.update(game, entity, game.frameTime)
+			}
+		}
 	}
 
-	// Update all game entites
-	for _, entity := range game.entities {
-		entity.Update()
+	game.camera.update(game.frameTime)
+	game.updateLayerCameras()
+
+	game.publishInput()
+
+	// deliver everything queued this frame, see EventBus.SetQueued
+	game.EventBus.Dispatch()
+}
+
+func (game *Game) publishInput() {
+	// read the inputs once so the publishers, the input map and any
+	// recording all agree
+	input, ok := game.readInput()
+	if !ok {
+		return
 	}
+	game.mouseScreenPos = input.Mouse.ScreenPosition
+	game.mousePosition = game.ScreenToWorld(game.mouseScreenPos)
+	input.Mouse.Position = game.mousePosition
+	game.input = input
 
 	// publish inputs if enabled
 	if game.inputs.MouseInputEnable {
-		mouseInputEvent := getMouseInputEvent()
-		game.EventBus.Publish("input.mouse", mouseInputEvent)
+		game.EventBus.Publish("input.mouse", input.Mouse)
 	}
 	if game.inputs.KeyboardInputEnable {
-		// game.EventBus.Publish("input.keyboard", keyboardInputEvent)
+		game.EventBus.Publish("input.keyboard", input.Keyboard)
 	}
 	if game.inputs.GamepadInputEnable {
-		// game.EventBus.Publish("input.gamepad", gamepadInputEvent)
+		game.publishGamepadConnections(input.Gamepad)
+		game.EventBus.Publish("input.gamepad", input.Gamepad)
+	}
+	if game.inputMap.hasBindings() {
+		for _, actionEvent := range game.inputMap.update(input.Keyboard, input.Mouse, input.Gamepad) {
+			publishIfSubscribed(&game.EventBus, "input.action", actionEvent)
+		}
 	}
-
-	// TODO: remove this
-	game.mousePosition = Vector2FromRaylib(rl.GetMousePosition())
 }
 
+// Draw draws each visible layer from the bottom up with its camera. Joints
+// are drawn in the world layer on top of its entities.
 func (game Game) Draw() {
-	for _, entity := range game.entities {
-		entity.Draw()
+	renderer := rendererFor(&game)
+	for i, entities := range game.drawOrder() {
+		layer := game.layers[i]
+		hasJoints := layer.name == LayerWorld && len(game.joints) > 0
+		if !layer.visible || (len(entities) == 0 && !hasJoints) {
+			continue
+		}
+		if !layer.screenSpace {
+			if layer.camera != nil {
+				renderer.BeginCamera(*layer.camera)
+			} else {
+				renderer.BeginCamera(game.camera)
+			}
+		}
+		for _, entity := range entities {
+			entity.Draw()
+		}
+		if hasJoints {
+			for _, joint := range game.joints {
+				joint.Draw()
+			}
+		}
+		if !layer.screenSpace {
+			renderer.EndCamera()
+		}
 	}
 }
 
-func (game *Game) Run() {
+func (game *Game) frame() {
+	if game.IsReplaying() {
+		game.frameTime = game.replay[game.replayIndex].FrameTime
+	} else if game.headless {
+		game.frameTime = 1.0 / float64(game.targetFPS)
+	} else {
+		game.frameTime = float64(rl.GetFrameTime())
+	}
 
-	for !rl.WindowShouldClose() {
+	game.Update()
+	if game.updateCallback != nil {
+		game.updateCallback(game)
+	}
 
-		game.Update()
-		if game.updateCallback != nil {
-			game.updateCallback(game)
-		}
+	if game.renderer == nil {
+		return
+	}
 
-		// ---------- Drawing ----------
-		rl.BeginDrawing()
-		rl.ClearBackground(game.backgroundColor)
-		game.Draw()
-		if game.drawCallback != nil {
-			game.drawCallback(game)
-		}
-		rl.EndDrawing()
-		// -----------------------------
+	// ---------- Drawing ----------
+	game.renderer.BeginFrame(game.backgroundColor)
+	game.Draw()
+	// the draw callback is in screen space so it can be used for HUDs
+	if game.drawCallback != nil {
+		game.drawCallback(game)
+	}
+	game.renderer.EndFrame()
+	// -----------------------------
+}
+
+func (game *Game) shouldClose() bool {
+	if game.closed {
+		return true
+	}
+	return !game.headless && rl.WindowShouldClose()
+}
+
+// Run runs the game loop until the window closes. If input was still being
+// recorded the recording is saved, and an error saving it is returned.
+func (game *Game) Run() error {
+
+	for !game.shouldClose() {
+		game.frame()
+	}
+
+	var err error
+	if game.IsRecording() {
+		err = game.StopRecording()
+	}
+
+	if !game.headless {
+		rl.CloseWindow()
+	}
+	return err
+}
+
+// RunFrames runs n frames of the game loop, or fewer if Close is called.
+// This is mostly useful for driving a headless game.
+func (game *Game) RunFrames(n int) {
+	for i := 0; i < n && !game.closed; i++ {
+		game.frame()
 	}
+}
 
-	rl.CloseWindow()
+// Close stops Run after the current frame
+func (game *Game) Close() {
+	game.closed = true
 }
 
+// AddEntity adds an entity to the game, adding one that is already in the
+// game does nothing
 func (game *Game) AddEntity(entity Entity) {
+	if registered, ok := game.registry[entity.Id()]; ok && registered == entity {
+		return
+	}
 	var body *cp.Body
 	var shape *cp.Shape
 	entity.addToGame(game, body, shape)
+	entity.base().savePhysicsState()
+}
+
+// registerEntity is called by each entity's addToGame. Ids are never reused, so
+// an entity keeps the id it was first given by this game if it is added again.
+func (game *Game) registerEntity(entity Entity) {
+	e := entity.base()
+	if e.id == 0 || e.game != game {
+		game.nextID++
+		e.id = game.nextID
+	}
+	e.game = game
+	e.generation++
+	game.layer(e.Layer())
+	// so collision callbacks can find their way back to the entity
+	e.eachShape(func(shape *cp.Shape) {
+		shape.UserData = entity
+		if e.filter != nil {
+			shape.SetFilter(*e.filter)
+		}
+		shape.SetSensor(e.sensor)
+	})
+	if e.cpBody != nil {
+		e.cpBody.UserData = entity
+	}
+	game.entities = append(game.entities, entity)
+	game.registry[e.id] = entity
+	delete(game.detached, e.id)
+}
+
+func (game *Game) EntityByID(id uint64) (Entity, bool) {
+	entity, ok := game.registry[id]
+	return entity, ok
+}
+
+// EntityByHandle is like EntityByID but fails if the entity has been removed
+// since the handle was taken
+func (game *Game) EntityByHandle(handle EntityHandle) (Entity, bool) {
+	entity, ok := game.registry[handle.ID]
+	if !ok || entity.base().generation != handle.Generation {
+		return nil, false
+	}
+	return entity, true
 }
 
+func (game *Game) IsValid(handle EntityHandle) bool {
+	_, ok := game.EntityByHandle(handle)
+	return ok
+}
+
+// RemoveEntity takes an entity out of the game for good, cancelling the
+// handlers it subscribed with OnClick, OnCollision and the like. That includes
+// entities already taken out by ClearEntities.
 func (game *Game) RemoveEntity(entity Entity) {
-	var found bool = false
-	var ind int
+	id := entity.Id()
+	if detached, ok := game.detached[id]; ok && detached == entity {
+		delete(game.detached, id)
+		entity.base().unsubscribeAll(game)
+		return
+	}
+	if registered, ok := game.registry[id]; !ok || registered != entity {
+		return
+	}
+	delete(game.registry, id)
 	for i, v := range game.entities {
-		if v.Id() == entity.Id() {
-			found = true
-			ind = i
+		if v.Id() == id {
+			game.entities = append(game.entities[:i], game.entities[i+1:]...)
+			break
 		}
 	}
-	if found {
-		game.entities = append(game.entities[:ind], game.entities[ind+1:]...)
+	game.detachEntity(entity)
+	entity.base().unsubscribeAll(game)
+}
+
+// detachEntity removes the entity's physics objects and runs its OnRemoved
+// callbacks. It is safe to call mid-step, e.g. from a collision callback.
+func (game *Game) detachEntity(entity Entity) {
+	e := entity.base()
+	game.removeEntityJoints(entity)
+	e.removeFromSpace(game)
+	for _, callback := range e.removedCallbacks {
+		callback()
 	}
 }
 
+// ClearEntities removes every entity but keeps the handlers they subscribed
+// with OnClick, OnCollision and the like, so they work again if the entities
+// are added back, as happens when switching between scenes. The handlers
+// don't fire while their entity is out of the game. Pass an entity that
+// won't come back to RemoveEntity to cancel its handlers.
 func (game *Game) ClearEntities() {
-	game.entities = game.entities[:0]
+	removed := game.entities
+	game.entities = nil
+	clear(game.registry)
+	for _, entity := range removed {
+		game.detached[entity.Id()] = entity
+		game.detachEntity(entity)
+	}
 }
 
 type Scene struct {
 	entities []Entity
+	joints   []*Joint
 }
 
 func NewScene() Scene {
@@ -240,8 +558,10 @@ func (s *Scene) AddEntity(entity Entity) {
 func (scene *Scene) RemoveEntity(entity Entity) {
 	var found bool = false
 	var ind int
+	// compare the entities themselves, ids aren't assigned until they're
+	// added to a game
 	for i, v := range scene.entities {
-		if v.Id() == entity.Id() {
+		if v == entity {
 			found = true
 			ind = i
 		}
@@ -251,9 +571,29 @@ func (scene *Scene) RemoveEntity(entity Entity) {
 	}
 }
 
-func (game *Game) SetScene(scene Scene) {
+// AddJoint adds a joint to the scene, its entities should be in the scene too
+func (s *Scene) AddJoint(joint *Joint) {
+	s.joints = append(s.joints, joint)
+}
+
+func (s Scene) Joints() []*Joint {
+	return s.joints
+}
+
+// SetScene replaces the game's entities and joints with the scene's. Every
+// entity is added even if some joints can't be, their errors are returned
+// together.
+func (game *Game) SetScene(scene Scene) error {
 	game.ClearEntities()
+	for _, joint := range append([]*Joint(nil), game.joints...) {
+		game.RemoveJoint(joint)
+	}
 	for i := range scene.entities {
 		game.AddEntity(scene.entities[i])
 	}
+	var errs []error
+	for _, joint := range scene.joints {
+		errs = append(errs, game.AddJoint(joint))
+	}
+	return errors.Join(errs...)
 }
@@ -0,0 +1,268 @@
+package raychip
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func TestHeadlessGravity(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	game.SetGravity(NewVector2(0, 1000))
+	ball := NewPhysicalCircle(400, 100, 10, 1, rl.Red)
+	game.AddEntity(&ball)
+
+	game.RunFrames(30)
+
+	// half a second of free fall covers about 0.5 * 1000 * 0.5^2 = 125
+	pos := ball.Position()
+	if pos.X != 400 {
+		t.Errorf("ball drifted sideways to x = %v", pos.X)
+	}
+	fallen := pos.Y - 100
+	if fallen < 110 || fallen > 140 {
+		t.Errorf("ball fell %v in half a second, want about 125", fallen)
+	}
+	if ball.Velocity().Y <= 0 {
+		t.Errorf("ball should still be falling, velocity %v", ball.Velocity())
+	}
+}
+
+func TestHeadlessWallCollision(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	game.SetGravity(NewVector2(0, 1000))
+	game.SetDamping(0.5)
+	floor := NewWall(NewVector2(0, 500), NewVector2(800, 500), 2, rl.Black)
+	game.AddEntity(&floor)
+	ball := NewPhysicalCircle(400, 100, 10, 1, rl.Red)
+	ball.SetElasticity(0)
+	game.AddEntity(&ball)
+
+	game.RunFrames(300)
+
+	// the ball rests on top of the floor, its radius plus half the wall above it
+	pos := ball.Position()
+	if pos.Y > 500 {
+		t.Fatalf("ball fell through the wall to y = %v", pos.Y)
+	}
+	if pos.Y < 485 || pos.Y > 490 {
+		t.Errorf("ball came to rest at y = %v, want about 489", pos.Y)
+	}
+}
+
+func newFallingBall() (*Game, *Circle) {
+	game := NewHeadlessGame(800, 600, 60)
+	game.SetGravity(NewVector2(0, 600))
+	ball := NewPhysicalCircle(400, 100, 10, 1, rl.Red)
+	game.AddEntity(&ball)
+	return &game, &ball
+}
+
+func TestFixedTimestep(t *testing.T) {
+	game, ball := newFallingBall()
+	game.SetPhysicsStep(1.0 / 120)
+
+	// half a step of frame time doesn't step the physics yet
+	game.frameTime = 1.0 / 240
+	game.Update()
+	if v := ball.Velocity().Y; v != 0 {
+		t.Errorf("physics stepped early, velocity %v", v)
+	}
+	if alpha := game.Alpha(); math.Abs(alpha-0.5) > 1e-9 {
+		t.Errorf("alpha is %v, want 0.5", alpha)
+	}
+
+	// the other half and one more step makes two
+	game.frameTime = 1.0/240 + 1.0/120
+	game.Update()
+	if v := ball.Velocity().Y; math.Abs(v-600*2.0/120) > 1e-9 {
+		t.Errorf("velocity is %v after two steps, want %v", v, 600*2.0/120)
+	}
+	if alpha := game.Alpha(); math.Abs(alpha) > 1e-9 {
+		t.Errorf("alpha is %v, want 0", alpha)
+	}
+}
+
+func TestMaxSubsteps(t *testing.T) {
+	game, ball := newFallingBall()
+	game.SetMaxSubsteps(3)
+
+	// a one second hitch is clamped to three steps
+	game.frameTime = 1
+	game.Update()
+	if v := ball.Velocity().Y; math.Abs(v-600*3.0/60) > 1e-9 {
+		t.Errorf("velocity is %v after a hitch, want %v", v, 600*3.0/60)
+	}
+	if alpha := game.Alpha(); alpha < 0 || alpha >= 1 {
+		t.Errorf("alpha is %v after a hitch", alpha)
+	}
+}
+
+func TestInterpolation(t *testing.T) {
+	game, ball := newFallingBall()
+	game.RunFrames(10)
+
+	prev, pos := ball.InterpolatedPosition(0), ball.Position()
+	if prev.Y >= pos.Y {
+		t.Fatalf("previous position %v isn't above current %v", prev, pos)
+	}
+	mid := ball.InterpolatedPosition(0.5)
+	if math.Abs(mid.Y-(prev.Y+pos.Y)/2) > 1e-9 {
+		t.Errorf("halfway position is %v, between %v and %v", mid, prev, pos)
+	}
+
+	// teleports aren't interpolated
+	ball.SetPosition(100, 100)
+	ball.SetAngle(1)
+	if p := ball.InterpolatedPosition(0.5); p != NewVector2(100, 100) {
+		t.Errorf("teleported ball is drawn at %v", p)
+	}
+	if a := ball.InterpolatedAngle(0.5); a != 1 {
+		t.Errorf("rotated ball is drawn at angle %v", a)
+	}
+}
+
+func TestEntityIDs(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	a := NewCircle(100, 100, 10, rl.Red)
+	b := NewCircle(200, 100, 10, rl.Red)
+	game.AddEntity(&a)
+	game.AddEntity(&b)
+	game.RemoveEntity(&a)
+	c := NewBox(300, 100, 10, 10, rl.Red)
+	game.AddEntity(&c)
+
+	if a.Id() == b.Id() || b.Id() == c.Id() || a.Id() == c.Id() {
+		t.Errorf("ids %d, %d and %d aren't unique", a.Id(), b.Id(), c.Id())
+	}
+	if _, ok := game.EntityByID(a.Id()); ok {
+		t.Error("removed entity is still found by id")
+	}
+	if found, ok := game.EntityByID(c.Id()); !ok || found != &c {
+		t.Errorf("EntityByID(%d) = %v, %v", c.Id(), found, ok)
+	}
+
+	// a handle goes stale once the entity is removed, even if it comes back
+	handle := b.Handle()
+	if !game.IsValid(handle) {
+		t.Error("handle to a live entity isn't valid")
+	}
+	game.RemoveEntity(&b)
+	game.AddEntity(&b)
+	if game.IsValid(handle) {
+		t.Error("handle survived the entity being removed")
+	}
+	if !game.IsValid(b.Handle()) {
+		t.Error("new handle isn't valid")
+	}
+}
+
+func TestAddEntityTwice(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	ball := NewPhysicalCircle(100, 100, 10, 1, rl.Red)
+	game.AddEntity(&ball)
+	handle := ball.Handle()
+	game.AddEntity(&ball)
+
+	if n := game.EntitiesCount(); n != 1 {
+		t.Errorf("game has %d entities, want 1", n)
+	}
+	if !game.IsValid(handle) {
+		t.Error("adding the entity again invalidated its handle")
+	}
+}
+
+func TestClickCircle(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	game.EnableMouseInput()
+	ball := NewCircle(300, 300, 30, rl.Black)
+	game.AddEntity(&ball)
+	pressed, released := 0, 0
+	ball.OnClick(&game, rl.MouseButtonLeft, MousePressed, func() { pressed++ })
+	ball.OnClick(&game, rl.MouseButtonLeft, MouseReleased, func() { released++ })
+	input := game.InputSource().(*ScriptedInput)
+
+	input.ClickAt(320, 310, rl.MouseButtonLeft)
+	game.RunFrames(2)
+	// inside the bounding square but outside the circle
+	input.ClickAt(325, 325, rl.MouseButtonLeft)
+	game.RunFrames(2)
+
+	if pressed != 1 || released != 1 {
+		t.Errorf("circle saw %d presses and %d releases, want 1 of each", pressed, released)
+	}
+}
+
+func TestClickBox(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	game.EnableMouseInput()
+	box := NewBox(500, 500, 50, 50, rl.Black)
+	game.AddEntity(&box)
+	clicks := 0
+	box.OnClick(&game, rl.MouseButtonLeft, MouseReleased, func() { clicks++ })
+	input := game.InputSource().(*ScriptedInput)
+
+	input.ClickAt(520, 480, rl.MouseButtonLeft)
+	game.RunFrames(2)
+	input.ClickAt(530, 500, rl.MouseButtonLeft)
+	game.RunFrames(2)
+	input.ClickAt(520, 480, rl.MouseButtonRight)
+	game.RunFrames(2)
+
+	if clicks != 1 {
+		t.Errorf("box was clicked %d times, want 1", clicks)
+	}
+}
+
+func TestGameBusQueuesOtherGoroutines(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	var got atomic.Int64
+	Subscribe(&game.EventBus, "ping", func(ping) { got.Add(1) })
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				game.EventBus.Publish("ping", ping{N: i})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := got.Load(); n != 0 {
+		t.Fatalf("%d pings were delivered off the game loop", n)
+	}
+	game.RunFrames(1)
+	if n := got.Load(); n != 200 {
+		t.Errorf("got %d pings after a frame, want 200", n)
+	}
+
+	// the loop's own messages aren't held back
+	game.EventBus.Publish("ping", ping{})
+	if n := got.Load(); n != 201 {
+		t.Errorf("game loop's publish wasn't delivered straight away")
+	}
+}
+
+func TestCollisionEventsDuringStep(t *testing.T) {
+	game, ball := newFallingBall()
+	floor := NewWall(NewVector2(0, 300), NewVector2(800, 300), 2, rl.Black)
+	game.AddEntity(&floor)
+	var duringStep []bool
+	ball.OnCollision(game, func(Entity, Contact) {
+		duringStep = append(duringStep, game.stepping)
+	})
+
+	game.RunFrames(60)
+
+	// collision events come from the game loop so they aren't held back
+	// until the end of the frame
+	if len(duringStep) == 0 || !duringStep[0] {
+		t.Errorf("collision callbacks ran during the step: %v", duringStep)
+	}
+}
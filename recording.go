@@ -0,0 +1,147 @@
+package raychip
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// recordingVersion is bumped whenever InputFrame changes shape
+const recordingVersion = 1
+
+type recordingHeader struct {
+	Version     int
+	TargetFPS   int32
+	PhysicsStep float64
+}
+
+// ReplayEnded is published on "input.replay.end" after the last recorded
+// frame has been replayed, the game goes back to live input after that
+type ReplayEnded struct {
+	Frames int
+}
+
+// inputRecorder writes a gzipped stream of gob encoded input frames
+type inputRecorder struct {
+	file    *os.File
+	zip     *gzip.Writer
+	encoder *gob.Encoder
+	err     error
+}
+
+func (r *inputRecorder) write(input InputFrame) {
+	if r.err == nil {
+		r.err = r.encoder.Encode(input)
+	}
+}
+
+func (r *inputRecorder) close() error {
+	return errors.Join(r.err, r.zip.Close(), r.file.Close())
+}
+
+// StartRecording saves the input of every frame from now on to a file that
+// StartReplay can play back. Run stops the recording when the window closes,
+// otherwise call StopRecording.
+func (game *Game) StartRecording(path string) error {
+	if game.IsRecording() {
+		if err := game.StopRecording(); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	zip := gzip.NewWriter(file)
+	recorder := &inputRecorder{file: file, zip: zip, encoder: gob.NewEncoder(zip)}
+	recorder.err = recorder.encoder.Encode(recordingHeader{
+		Version:     recordingVersion,
+		TargetFPS:   game.targetFPS,
+		PhysicsStep: game.physicsStep,
+	})
+	if recorder.err != nil {
+		return recorder.close()
+	}
+
+	game.recorder = recorder
+	return nil
+}
+
+// StopRecording finishes writing the recording file
+func (game *Game) StopRecording() error {
+	if game.recorder == nil {
+		return nil
+	}
+	err := game.recorder.close()
+	game.recorder = nil
+	return err
+}
+
+func (game Game) IsRecording() bool {
+	return game.recorder != nil
+}
+
+// StartReplay plays back a recording made with StartRecording. Until it ends
+// the recorded input is published on the EventBus instead of raylib's, and
+// each frame takes as long as it did when recorded, so with a fixed physics
+// step the game plays out exactly as it did. Replays also work headless.
+func (game *Game) StartReplay(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	zip, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	decoder := gob.NewDecoder(zip)
+
+	var header recordingHeader
+	if err := decoder.Decode(&header); err != nil {
+		return err
+	}
+	if header.Version != recordingVersion {
+		return fmt.Errorf("unsupported recording version %d", header.Version)
+	}
+
+	var frames []InputFrame
+	for {
+		var input InputFrame
+		err := decoder.Decode(&input)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		frames = append(frames, input)
+	}
+	if len(frames) == 0 {
+		return nil
+	}
+
+	game.physicsStep = header.PhysicsStep
+	game.replay = frames
+	game.replayIndex = 0
+	return nil
+}
+
+// StopReplay goes back to live input
+func (game *Game) StopReplay() {
+	game.replay = nil
+	game.replayIndex = 0
+}
+
+func (game Game) IsReplaying() bool {
+	return game.replayIndex < len(game.replay)
+}
+
+// ReplayFrame is how many frames of the replay have been played so far
+func (game Game) ReplayFrame() int {
+	return game.replayIndex
+}
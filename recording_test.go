@@ -0,0 +1,157 @@
+package raychip
+
+import (
+	"path/filepath"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// newSteeredBall makes a game where the arrow keys push a ball around
+func newSteeredBall() (*Game, *Circle) {
+	game := NewHeadlessGame(800, 600, 60)
+	game.EnableKeyboardInput()
+	ball := NewPhysicalCircle(400, 300, 10, 1, rl.Red)
+	game.AddEntity(&ball)
+	game.OnKey(rl.KeyRight, KeyDown, func() {
+		v := ball.Velocity()
+		ball.SetVelocity(v.X+20, v.Y)
+	})
+	game.OnKey(rl.KeyDown, KeyDown, func() {
+		v := ball.Velocity()
+		ball.SetVelocity(v.X, v.Y+20)
+	})
+	return &game, &ball
+}
+
+// writeRecording records frames of held keys, a headless game has no input of
+// its own to record
+func writeRecording(t *testing.T, path string, frames [][]int32) {
+	game := NewHeadlessGame(800, 600, 60)
+	if err := game.StartRecording(path); err != nil {
+		t.Fatalf("StartRecording: %v", err)
+	}
+	for _, keys := range frames {
+		game.recorder.write(InputFrame{FrameTime: 1.0 / 60, Keyboard: keysDown(keys...)})
+	}
+	if err := game.StopRecording(); err != nil {
+		t.Fatalf("StopRecording: %v", err)
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "input.rec")
+	var frames [][]int32
+	for i := 0; i < 45; i++ {
+		switch {
+		case i < 20:
+			frames = append(frames, []int32{rl.KeyRight})
+		case i < 30:
+			frames = append(frames, []int32{rl.KeyRight, rl.KeyDown})
+		default:
+			frames = append(frames, []int32{rl.KeyDown})
+		}
+	}
+	writeRecording(t, path, frames)
+
+	var positions []Vector2
+	for run := 0; run < 2; run++ {
+		game, ball := newSteeredBall()
+		var ended []ReplayEnded
+		game.EventBus.CreateSubscription("input.replay.end", ReplayEnded{}, func(e ReplayEnded) {
+			ended = append(ended, e)
+		})
+		if err := game.StartReplay(path); err != nil {
+			t.Fatalf("StartReplay: %v", err)
+		}
+		game.RunFrames(45)
+		if game.IsReplaying() || game.ReplayFrame() != 0 {
+			t.Error("replay didn't end after the recorded frames")
+		}
+		if len(ended) != 1 || ended[0].Frames != 45 {
+			t.Errorf("got replay end events %+v", ended)
+		}
+		positions = append(positions, ball.Position())
+	}
+
+	if pos := positions[0]; pos.X <= 400 || pos.Y <= 300 {
+		t.Errorf("replayed input left the ball at %v", pos)
+	}
+	if positions[0] != positions[1] {
+		t.Errorf("replays left the ball at %v and %v", positions[0], positions[1])
+	}
+}
+
+func TestReplayMouseInWorld(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "input.rec")
+	game := NewHeadlessGame(800, 600, 60)
+	if err := game.StartRecording(path); err != nil {
+		t.Fatalf("StartRecording: %v", err)
+	}
+	game.recorder.write(InputFrame{FrameTime: 1.0 / 60, Mouse: MouseInputEvent{ScreenPosition: NewVector2(400, 300)}})
+	if err := game.StopRecording(); err != nil {
+		t.Fatalf("StopRecording: %v", err)
+	}
+
+	game = NewHeadlessGame(800, 600, 60)
+	game.EnableMouseInput()
+	game.Camera().SetTarget(NewVector2(1000, 1000))
+	var published Vector2
+	game.EventBus.CreateSubscription("input.mouse", MouseInputEvent{}, func(e MouseInputEvent) {
+		published = e.Position
+	})
+	if err := game.StartReplay(path); err != nil {
+		t.Fatalf("StartReplay: %v", err)
+	}
+	game.RunFrames(1)
+
+	want := NewVector2(1000, 1000)
+	if game.MousePosition() != want || game.input.Mouse.Position != want || published != want {
+		t.Errorf("mouse at %v, frame input %v, published %v, want %v", game.MousePosition(), game.input.Mouse.Position, published, want)
+	}
+}
+
+func TestStartReplayMissingFile(t *testing.T) {
+	game := NewHeadlessGame(800, 600, 60)
+	if err := game.StartReplay(filepath.Join(t.TempDir(), "missing.rec")); err == nil {
+		t.Error("replaying a missing file didn't fail")
+	}
+	if game.IsReplaying() {
+		t.Error("game is replaying after a failed start")
+	}
+}
+
+func TestRecordScriptedInput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "input.rec")
+
+	game, ball := newSteeredBall()
+	if err := game.StartRecording(path); err != nil {
+		t.Fatalf("StartRecording: %v", err)
+	}
+	input := game.InputSource().(*ScriptedInput)
+	input.PressKey(rl.KeyRight)
+	game.RunFrames(20)
+	input.PressKey(rl.KeyDown)
+	game.RunFrames(10)
+	input.ReleaseKey(rl.KeyRight)
+	game.RunFrames(15)
+	if err := game.StopRecording(); err != nil {
+		t.Fatalf("StopRecording: %v", err)
+	}
+	recorded := ball.Position()
+	if recorded == NewVector2(400, 300) {
+		t.Fatal("recorded input didn't move the ball")
+	}
+
+	game, ball = newSteeredBall()
+	if err := game.StartReplay(path); err != nil {
+		t.Fatalf("StartReplay: %v", err)
+	}
+	game.RunFrames(45)
+	if game.IsReplaying() {
+		t.Error("replay didn't end after the recorded frames")
+	}
+	if replayed := ball.Position(); replayed != recorded {
+		t.Errorf("replay left the ball at %v, recording left it at %v", replayed, recorded)
+	}
+}
@@ -0,0 +1,184 @@
+package raychip
+
+import (
+	"fmt"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+type DrawOp int
+
+const (
+	OpBeginFrame DrawOp = iota
+	OpEndFrame
+	OpBeginCamera
+	OpEndCamera
+	OpRectangle
+	OpCircle
+	OpLine
+	OpPolygon
+	OpTexture
+	OpText
+)
+
+var drawOpNames = map[DrawOp]string{
+	OpBeginFrame:  "begin_frame",
+	OpEndFrame:    "end_frame",
+	OpBeginCamera: "begin_camera",
+	OpEndCamera:   "end_camera",
+	OpRectangle:   "rectangle",
+	OpCircle:      "circle",
+	OpLine:        "line",
+	OpPolygon:     "polygon",
+	OpTexture:     "texture",
+	OpText:        "text",
+}
+
+func (op DrawOp) String() string {
+	if name, ok := drawOpNames[op]; ok {
+		return name
+	}
+	return fmt.Sprintf("DrawOp(%d)", int(op))
+}
+
+// DrawCommand is one call made to a RecordingRenderer, only the fields the
+// op uses are set. Points holds the centre of a circle, the ends of a line,
+// the corners of a polygon or the position of text.
+type DrawCommand struct {
+	Op        DrawOp
+	Points    []Vector2
+	Rect      rl.Rectangle
+	Source    rl.Rectangle
+	Origin    Vector2
+	Rotation  float64
+	Radius    float64
+	Thickness float64
+	FontSize  float64
+	Color     rl.Color
+	Texture   uint32
+	Text      string
+	Camera    Camera
+}
+
+// String formats the command on one line for comparing against saved output
+func (c DrawCommand) String() string {
+	var b strings.Builder
+	b.WriteString(c.Op.String())
+	color := func() {
+		fmt.Fprintf(&b, " color=%d,%d,%d,%d", c.Color.R, c.Color.G, c.Color.B, c.Color.A)
+	}
+	switch c.Op {
+	case OpBeginFrame:
+		color()
+	case OpBeginCamera:
+		fmt.Fprintf(&b, " target=%.2f,%.2f zoom=%.2f rotation=%.2f", c.Camera.target.X, c.Camera.target.Y, c.Camera.zoom, c.Camera.rotation)
+	case OpRectangle, OpTexture:
+		if c.Op == OpTexture {
+			fmt.Fprintf(&b, " texture=%d source=%.2f,%.2f,%.2f,%.2f", c.Texture, c.Source.X, c.Source.Y, c.Source.Width, c.Source.Height)
+		}
+		fmt.Fprintf(&b, " rect=%.2f,%.2f,%.2f,%.2f origin=%.2f,%.2f rotation=%.2f", c.Rect.X, c.Rect.Y, c.Rect.Width, c.Rect.Height, c.Origin.X, c.Origin.Y, c.Rotation)
+		color()
+	case OpCircle:
+		fmt.Fprintf(&b, " center=%.2f,%.2f radius=%.2f", c.Points[0].X, c.Points[0].Y, c.Radius)
+		color()
+	case OpLine:
+		fmt.Fprintf(&b, " from=%.2f,%.2f to=%.2f,%.2f thickness=%.2f", c.Points[0].X, c.Points[0].Y, c.Points[1].X, c.Points[1].Y, c.Thickness)
+		color()
+	case OpPolygon:
+		b.WriteString(" points=")
+		for i, p := range c.Points {
+			if i > 0 {
+				b.WriteString(";")
+			}
+			fmt.Fprintf(&b, "%.2f,%.2f", p.X, p.Y)
+		}
+		color()
+	case OpText:
+		fmt.Fprintf(&b, " text=%q at=%.2f,%.2f size=%.2f", c.Text, c.Points[0].X, c.Points[0].Y, c.FontSize)
+		color()
+	}
+	return b.String()
+}
+
+// RecordingRenderer keeps every draw call as a DrawCommand instead of
+// drawing, for tests that check what was drawn
+type RecordingRenderer struct {
+	commands []DrawCommand
+}
+
+func NewRecordingRenderer() *RecordingRenderer {
+	return &RecordingRenderer{}
+}
+
+// Commands is everything drawn so far
+func (r *RecordingRenderer) Commands() []DrawCommand {
+	return r.commands
+}
+
+// LastFrame is everything drawn since the last BeginFrame
+func (r *RecordingRenderer) LastFrame() []DrawCommand {
+	for i := len(r.commands) - 1; i >= 0; i-- {
+		if r.commands[i].Op == OpBeginFrame {
+			return r.commands[i:]
+		}
+	}
+	return r.commands
+}
+
+func (r *RecordingRenderer) Reset() {
+	r.commands = nil
+}
+
+// String is every command on its own line
+func (r *RecordingRenderer) String() string {
+	lines := make([]string, len(r.commands))
+	for i, c := range r.commands {
+		lines[i] = c.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (r *RecordingRenderer) record(c DrawCommand) {
+	r.commands = append(r.commands, c)
+}
+
+func (r *RecordingRenderer) BeginFrame(background rl.Color) {
+	r.record(DrawCommand{Op: OpBeginFrame, Color: background})
+}
+
+func (r *RecordingRenderer) EndFrame() {
+	r.record(DrawCommand{Op: OpEndFrame})
+}
+
+func (r *RecordingRenderer) BeginCamera(camera Camera) {
+	r.record(DrawCommand{Op: OpBeginCamera, Camera: camera})
+}
+
+func (r *RecordingRenderer) EndCamera() {
+	r.record(DrawCommand{Op: OpEndCamera})
+}
+
+func (r *RecordingRenderer) DrawRectangle(rect rl.Rectangle, origin Vector2, rotation float64, color rl.Color) {
+	r.record(DrawCommand{Op: OpRectangle, Rect: rect, Origin: origin, Rotation: rotation, Color: color})
+}
+
+func (r *RecordingRenderer) DrawCircle(center Vector2, radius float64, color rl.Color) {
+	r.record(DrawCommand{Op: OpCircle, Points: []Vector2{center}, Radius: radius, Color: color})
+}
+
+func (r *RecordingRenderer) DrawLine(start Vector2, end Vector2, thickness float64, color rl.Color) {
+	r.record(DrawCommand{Op: OpLine, Points: []Vector2{start, end}, Thickness: thickness, Color: color})
+}
+
+func (r *RecordingRenderer) DrawPolygon(points []Vector2, color rl.Color) {
+	r.record(DrawCommand{Op: OpPolygon, Points: append([]Vector2(nil), points...), Color: color})
+}
+
+func (r *RecordingRenderer) DrawTexture(texture rl.Texture2D, src rl.Rectangle, dest rl.Rectangle, origin Vector2, rotation float64, tint rl.Color) {
+	r.record(DrawCommand{Op: OpTexture, Texture: texture.ID, Source: src, Rect: dest, Origin: origin, Rotation: rotation, Color: tint})
+}
+
+func (r *RecordingRenderer) DrawText(text string, position Vector2, fontSize float64, color rl.Color) {
+	r.record(DrawCommand{Op: OpText, Text: text, Points: []Vector2{position}, FontSize: fontSize, Color: color})
+}
@@ -0,0 +1,122 @@
+package raychip
+
+import (
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Renderer is what the game and its entities draw with. Positions are in
+// world coordinates between BeginCamera and EndCamera and screen coordinates
+// otherwise, and rotations are in radians like entity angles.
+type Renderer interface {
+	BeginFrame(background rl.Color)
+	EndFrame()
+	BeginCamera(camera Camera)
+	EndCamera()
+	// DrawRectangle draws rect rotated about origin, which is relative to
+	// the rectangle's corner and ends up at rect.X, rect.Y
+	DrawRectangle(rect rl.Rectangle, origin Vector2, rotation float64, color rl.Color)
+	DrawCircle(center Vector2, radius float64, color rl.Color)
+	DrawLine(start Vector2, end Vector2, thickness float64, color rl.Color)
+	// DrawPolygon fills a convex polygon wound either way
+	DrawPolygon(points []Vector2, color rl.Color)
+	// DrawTexture draws the src part of a texture into dest, placed and
+	// rotated the same way as DrawRectangle. A negative src width or height
+	// flips the texture like it does in raylib.
+	DrawTexture(texture rl.Texture2D, src rl.Rectangle, dest rl.Rectangle, origin Vector2, rotation float64, tint rl.Color)
+	DrawText(text string, position Vector2, fontSize float64, color rl.Color)
+}
+
+func (game *Game) SetRenderer(renderer Renderer) {
+	game.renderer = renderer
+}
+
+// Renderer is the game's renderer. Games made with NewGame draw with raylib,
+// headless games don't draw at all unless given a renderer.
+func (game Game) Renderer() Renderer {
+	return game.renderer
+}
+
+// rendererFor is what things in game draw with, raylib if they aren't in a
+// game that has a renderer
+func rendererFor(game *Game) Renderer {
+	if game != nil && game.renderer != nil {
+		return game.renderer
+	}
+	return RaylibRenderer{}
+}
+
+func (e *EntityBase) renderer() Renderer {
+	return rendererFor(e.game)
+}
+
+func toDegrees(radians float64) float32 {
+	return float32(radians * 180.0 / math.Pi)
+}
+
+// RaylibRenderer draws to the window with raylib
+type RaylibRenderer struct{}
+
+func (RaylibRenderer) BeginFrame(background rl.Color) {
+	rl.BeginDrawing()
+	rl.ClearBackground(background)
+}
+
+func (RaylibRenderer) EndFrame() {
+	rl.EndDrawing()
+}
+
+func (RaylibRenderer) BeginCamera(camera Camera) {
+	rl.BeginMode2D(camera.ToRaylib())
+}
+
+func (RaylibRenderer) EndCamera() {
+	rl.EndMode2D()
+}
+
+func (RaylibRenderer) DrawRectangle(rect rl.Rectangle, origin Vector2, rotation float64, color rl.Color) {
+	rl.DrawRectanglePro(rect, origin.ToRaylib(), toDegrees(rotation), color)
+}
+
+func (RaylibRenderer) DrawCircle(center Vector2, radius float64, color rl.Color) {
+	rl.DrawCircleV(center.ToRaylib(), float32(radius), color)
+}
+
+func (RaylibRenderer) DrawLine(start Vector2, end Vector2, thickness float64, color rl.Color) {
+	rl.DrawLineEx(start.ToRaylib(), end.ToRaylib(), float32(thickness), color)
+}
+
+func (RaylibRenderer) DrawPolygon(points []Vector2, color rl.Color) {
+	// raylib wants the opposite winding to chipmunk on screen
+	reverse := signedArea(points) > 0
+	fan := make([]rl.Vector2, len(points))
+	for i, v := range points {
+		if reverse {
+			fan[len(points)-1-i] = v.ToRaylib()
+		} else {
+			fan[i] = v.ToRaylib()
+		}
+	}
+	rl.DrawTriangleFan(fan, color)
+}
+
+func (RaylibRenderer) DrawTexture(texture rl.Texture2D, src rl.Rectangle, dest rl.Rectangle, origin Vector2, rotation float64, tint rl.Color) {
+	rl.DrawTexturePro(texture, src, dest, origin.ToRaylib(), toDegrees(rotation), tint)
+}
+
+func (RaylibRenderer) DrawText(text string, position Vector2, fontSize float64, color rl.Color) {
+	rl.DrawText(text, int32(position.X), int32(position.Y), int32(fontSize), color)
+}
+
+// rectangleCorners are the corners of a rectangle drawn by DrawRectangle
+func rectangleCorners(rect rl.Rectangle, origin Vector2, rotation float64) []Vector2 {
+	w, h := float64(rect.Width), float64(rect.Height)
+	local := []Vector2{
+		NewVector2(-origin.X, -origin.Y),
+		NewVector2(w-origin.X, -origin.Y),
+		NewVector2(w-origin.X, h-origin.Y),
+		NewVector2(-origin.X, h-origin.Y),
+	}
+	return transformVertices(local, NewVector2(float64(rect.X), float64(rect.Y)), rotation)
+}
@@ -0,0 +1,74 @@
+package raychip
+
+import (
+	"flag"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func newDrawnScene(renderer Renderer) *Game {
+	game := NewHeadlessGame(200, 100, 60)
+	game.SetRenderer(renderer)
+	box := NewBox(50, 50, 40, 20, rl.Red)
+	game.AddEntity(&box)
+	ball := NewCircle(150, 50, 20, rl.Blue)
+	game.AddEntity(&ball)
+	game.SetDrawCallback(func(game *Game) {
+		game.Renderer().DrawText("score 10", NewVector2(5, 5), 12, rl.Black)
+	})
+	return &game
+}
+
+func TestImageRenderer(t *testing.T) {
+	renderer := NewImageRenderer(200, 100)
+	game := newDrawnScene(renderer)
+	game.RunFrames(1)
+
+	img := renderer.Image()
+	background := color.RGBA{R: rl.RayWhite.R, G: rl.RayWhite.G, B: rl.RayWhite.B, A: 255}
+	tests := []struct {
+		x, y int
+		want color.RGBA
+	}{
+		{50, 50, color.RGBA{R: rl.Red.R, G: rl.Red.G, B: rl.Red.B, A: 255}},
+		{69, 59, color.RGBA{R: rl.Red.R, G: rl.Red.G, B: rl.Red.B, A: 255}},
+		{71, 50, background},
+		{50, 61, background},
+		{150, 50, color.RGBA{R: rl.Blue.R, G: rl.Blue.G, B: rl.Blue.B, A: 255}},
+		{150, 31, color.RGBA{R: rl.Blue.R, G: rl.Blue.G, B: rl.Blue.B, A: 255}},
+		// inside the circle's bounding square but not the circle
+		{166, 34, background},
+	}
+	for _, test := range tests {
+		if got := img.RGBAAt(test.x, test.y); got != test.want {
+			t.Errorf("pixel at %d,%d is %v, want %v", test.x, test.y, got, test.want)
+		}
+	}
+}
+
+func TestRecordingRendererGolden(t *testing.T) {
+	renderer := NewRecordingRenderer()
+	game := newDrawnScene(renderer)
+	game.RunFrames(1)
+
+	got := renderer.String() + "\n"
+	path := filepath.Join("testdata", "recording.golden")
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("draw commands differ from %s\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
@@ -0,0 +1,248 @@
+package raychip
+
+import (
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Sprite draws a texture, or one frame of a sprite sheet. It can stand on its
+// own or be attached to another entity to follow it around as its visual.
+// The entity's color is used as the tint.
+type Sprite struct {
+	EntityBase
+	texture   rl.Texture2D
+	sheet     *SpriteSheet
+	frame     SpriteFrame
+	origin    Vector2
+	hasOrigin bool
+	scale     Vector2
+	flipX     bool
+	flipY     bool
+
+	attached     Entity
+	attachOffset Vector2
+
+	updateCallback func(*Sprite)
+	drawCallback   func(*Sprite)
+}
+
+// NewSprite draws the whole texture centred on x, y
+func NewSprite(x float64, y float64, texture rl.Texture2D) Sprite {
+	width, height := float64(texture.Width), float64(texture.Height)
+	sOut := Sprite{
+		EntityBase: EntityBase{
+			position: NewVector2(x, y),
+			color:    rl.White,
+		},
+		texture: texture,
+		frame: SpriteFrame{
+			Source: rl.NewRectangle(0, 0, float32(width), float32(height)),
+			Size:   NewVector2(width, height),
+		},
+		scale: NewVector2(1, 1),
+	}
+	sOut.SetDrawCallback(defaultSpriteDrawFunc)
+	return sOut
+}
+
+// NewSheetSprite draws frame i of a sprite sheet centred on x, y
+func NewSheetSprite(x float64, y float64, sheet SpriteSheet, i int) Sprite {
+	sOut := NewSprite(x, y, sheet.Texture())
+	sOut.sheet = &sheet
+	sOut.frame = sheet.Frame(i)
+	return sOut
+}
+
+func (s *Sprite) addToGame(game *Game, args ...any) {
+	game.registerEntity(s)
+}
+
+func (s *Sprite) Update() {
+	// keep Position and Angle in step with whatever the sprite is attached to
+	if s.attached != nil {
+		target := s.attached.base()
+		s.position = s.attachPoint(target.Position(), target.Angle())
+	}
+	if s.updateCallback != nil {
+		s.updateCallback(s)
+	}
+}
+
+func (s *Sprite) Draw() {
+	if s.drawCallback != nil {
+		s.drawCallback(s)
+	}
+}
+
+func defaultSpriteDrawFunc(s *Sprite) {
+	if s.attached != nil {
+		target := s.attached.base()
+		angle := target.RenderAngle()
+		s.drawAt(s.renderer(), s.attachPoint(target.RenderPosition(), angle), angle+s.angle)
+		return
+	}
+	s.drawAt(s.renderer(), s.RenderPosition(), s.RenderAngle())
+}
+
+func (s Sprite) DefaultDraw() {
+	defaultSpriteDrawFunc(&s)
+}
+
+func (s *Sprite) SetDrawCallback(callback func(*Sprite)) {
+	s.drawCallback = callback
+}
+
+func (s *Sprite) SetUpdateCallback(callback func(*Sprite)) {
+	s.updateCallback = callback
+}
+
+// drawAt draws the current frame with its origin at pos
+func (s *Sprite) drawAt(renderer Renderer, pos Vector2, angle float64) {
+	frame := s.frame
+	src := frame.Source
+	offset := frame.Offset
+	// raylib flips a texture when the source size is negative, the trimmed
+	// part of the frame has to be mirrored within the full frame as well
+	if s.flipX {
+		offset.X = frame.Size.X - offset.X - float64(src.Width)
+		src.Width = -src.Width
+	}
+	if s.flipY {
+		offset.Y = frame.Size.Y - offset.Y - float64(src.Height)
+		src.Height = -src.Height
+	}
+	origin := s.Origin()
+	dest := rl.NewRectangle(
+		float32(pos.X),
+		float32(pos.Y),
+		float32(math.Abs(float64(src.Width))*s.scale.X),
+		float32(math.Abs(float64(src.Height))*s.scale.Y),
+	)
+	destOrigin := NewVector2((origin.X-offset.X)*s.scale.X, (origin.Y-offset.Y)*s.scale.Y)
+	renderer.DrawTexture(s.texture, src, dest, destOrigin, angle, s.color)
+}
+
+// Attach makes the sprite follow an entity, offset in the entity's own
+// frame, and turn with it. The sprite still has to be added to the game, and
+// is removed from it along with the entity.
+func (s *Sprite) Attach(entity Entity, offset Vector2) {
+	s.attached = entity
+	s.attachOffset = offset
+	entity.base().OnRemoved(func() {
+		if s.attached == entity && s.game != nil {
+			s.game.RemoveEntity(s)
+		}
+	})
+}
+
+// attachPoint is where the sprite goes on an entity at pos turned by angle
+func (s *Sprite) attachPoint(pos Vector2, angle float64) Vector2 {
+	return transformVertices([]Vector2{s.attachOffset}, pos, angle)[0]
+}
+
+func (s *Sprite) Detach() {
+	s.attached = nil
+}
+
+func (s Sprite) Attached() Entity {
+	return s.attached
+}
+
+func (s Sprite) Texture() rl.Texture2D {
+	return s.texture
+}
+
+func (s Sprite) Frame() SpriteFrame {
+	return s.frame
+}
+
+// SetFrame draws part of the sprite's texture, e.g. a frame from a sheet
+// made from the same texture
+func (s *Sprite) SetFrame(frame SpriteFrame) {
+	s.frame = frame
+}
+
+// SetSource draws the src rectangle of the sprite's texture
+func (s *Sprite) SetSource(src rl.Rectangle) {
+	s.frame = SpriteFrame{
+		Source: src,
+		Size:   NewVector2(float64(src.Width), float64(src.Height)),
+	}
+}
+
+// SetSheet switches to drawing frame i of another sheet
+func (s *Sprite) SetSheet(sheet SpriteSheet, i int) {
+	s.sheet = &sheet
+	s.texture = sheet.Texture()
+	s.frame = sheet.Frame(i)
+}
+
+// SetFrameIndex shows frame i of the sprite's sheet
+func (s *Sprite) SetFrameIndex(i int) {
+	if s.sheet != nil {
+		s.frame = s.sheet.Frame(i)
+	}
+}
+
+// SetFrameName shows a named frame of the sprite's sheet, and reports
+// whether it was found
+func (s *Sprite) SetFrameName(name string) bool {
+	if s.sheet == nil {
+		return false
+	}
+	frame, ok := s.sheet.FrameByName(name)
+	if ok {
+		s.frame = frame
+	}
+	return ok
+}
+
+// SetOrigin sets the point of the frame, in unscaled pixels from its top
+// left corner, that is placed at the sprite's position and rotated about.
+// The centre of the frame is used until this is called.
+func (s *Sprite) SetOrigin(origin Vector2) {
+	s.origin = origin
+	s.hasOrigin = true
+}
+
+func (s Sprite) Origin() Vector2 {
+	if !s.hasOrigin {
+		return NewVector2(s.frame.Size.X/2, s.frame.Size.Y/2)
+	}
+	return s.origin
+}
+
+func (s *Sprite) SetScale(x float64, y float64) {
+	s.scale = NewVector2(x, y)
+}
+
+func (s Sprite) Scale() Vector2 {
+	return s.scale
+}
+
+func (s *Sprite) SetFlip(flipX bool, flipY bool) {
+	s.flipX = flipX
+	s.flipY = flipY
+}
+
+func (s Sprite) FlipX() bool {
+	return s.flipX
+}
+
+func (s Sprite) FlipY() bool {
+	return s.flipY
+}
+
+func (s *Sprite) SetTint(tint rl.Color) {
+	s.color = tint
+}
+
+func (s Sprite) Tint() rl.Color {
+	return s.color
+}
+
+// Size is how big the sprite is drawn, before rotation
+func (s Sprite) Size() Vector2 {
+	return NewVector2(s.frame.Size.X*s.scale.X, s.frame.Size.Y*s.scale.Y)
+}
@@ -0,0 +1,97 @@
+package raychip
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func TestSpriteFlipOffsets(t *testing.T) {
+	// an 8x6 frame trimmed out of a 10x12 one, 2 pixels in from the left and 1 from the top
+	frame := SpriteFrame{
+		Source: rl.NewRectangle(20, 30, 8, 6),
+		Offset: NewVector2(2, 1),
+		Size:   NewVector2(10, 12),
+	}
+	tests := []struct {
+		name         string
+		flipX, flipY bool
+		src          rl.Rectangle
+		origin       Vector2
+	}{
+		{"unflipped", false, false, rl.NewRectangle(20, 30, 8, 6), NewVector2(3, 5)},
+		{"flip x", true, false, rl.NewRectangle(20, 30, -8, 6), NewVector2(5, 5)},
+		{"flip y", false, true, rl.NewRectangle(20, 30, 8, -6), NewVector2(3, 1)},
+		{"flip both", true, true, rl.NewRectangle(20, 30, -8, -6), NewVector2(5, 1)},
+	}
+	for _, test := range tests {
+		sprite := NewSprite(0, 0, rl.Texture2D{ID: 1, Width: 64, Height: 64})
+		sprite.SetFrame(frame)
+		sprite.SetFlip(test.flipX, test.flipY)
+		renderer := NewRecordingRenderer()
+		sprite.drawAt(renderer, NewVector2(100, 50), 0)
+
+		commands := renderer.Commands()
+		if len(commands) != 1 {
+			t.Fatalf("%s: %d draw commands, want 1", test.name, len(commands))
+		}
+		c := commands[0]
+		if c.Source != test.src {
+			t.Errorf("%s: source %v, want %v", test.name, c.Source, test.src)
+		}
+		if c.Rect != rl.NewRectangle(100, 50, 8, 6) {
+			t.Errorf("%s: dest %v, want 8x6 at 100,50", test.name, c.Rect)
+		}
+		if !near(c.Origin, test.origin) {
+			t.Errorf("%s: origin %v, want %v", test.name, c.Origin, test.origin)
+		}
+	}
+}
+
+func TestSpriteFlipPixels(t *testing.T) {
+	texture := rl.Texture2D{ID: 1, Width: 2, Height: 1}
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.NRGBA{R: 255, A: 255})
+	img.Set(1, 0, color.NRGBA{B: 255, A: 255})
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+
+	for _, flip := range []bool{false, true} {
+		renderer := NewImageRenderer(20, 10)
+		renderer.AddTexture(texture, img)
+		sprite := NewSprite(10, 5, texture)
+		sprite.SetScale(4, 4)
+		sprite.SetFlip(flip, false)
+		sprite.drawAt(renderer, sprite.Position(), 0)
+
+		left, right := renderer.Image().RGBAAt(7, 5), renderer.Image().RGBAAt(13, 5)
+		wantLeft, wantRight := red, blue
+		if flip {
+			wantLeft, wantRight = blue, red
+		}
+		if left != wantLeft || right != wantRight {
+			t.Errorf("flip %v: drew %v then %v, want %v then %v", flip, left, right, wantLeft, wantRight)
+		}
+	}
+}
+
+func TestSpriteAttach(t *testing.T) {
+	game := NewHeadlessGame(200, 100, 60)
+	box := NewBox(50, 50, 20, 20, rl.Red)
+	game.AddEntity(&box)
+	sprite := NewSprite(0, 0, rl.Texture2D{Width: 8, Height: 8})
+	game.AddEntity(&sprite)
+	sprite.Attach(&box, NewVector2(10, 0))
+
+	game.RunFrames(1)
+	if got := sprite.Position(); !near(got, NewVector2(60, 50)) {
+		t.Errorf("attached sprite at %v, want (60, 50)", got)
+	}
+
+	game.RemoveEntity(&box)
+	if sprite.inGame(&game) {
+		t.Errorf("sprite stayed in the game after the entity it was attached to was removed")
+	}
+}
@@ -0,0 +1,287 @@
+package raychip
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// SpriteFrame is one image in a sprite sheet. Source is where it is in the
+// texture. Atlases may trim the blank edges off a frame, in which case Size is
+// the untrimmed size and Offset is where Source sits within it.
+type SpriteFrame struct {
+	Name   string
+	Source rl.Rectangle
+	Offset Vector2
+	Size   Vector2
+	// Duration is how long the frame is shown for in seconds, if the atlas
+	// says, as Aseprite's do
+	Duration float64
+}
+
+// SpriteSheet slices one texture into frames, either on a grid or from an
+// atlas
+type SpriteSheet struct {
+	texture rl.Texture2D
+	frames  []SpriteFrame
+	names   map[string]int
+	columns int
+}
+
+// SpriteGrid describes a sheet of equally sized tiles. Columns and Rows can
+// be left at zero to fit as many tiles as the texture holds.
+type SpriteGrid struct {
+	TileWidth  int
+	TileHeight int
+	SpacingX   int
+	SpacingY   int
+	Columns    int
+	Rows       int
+}
+
+// NewGridSpriteSheet slices a texture into tiles numbered left to right, top
+// to bottom. If the texture is exactly Columns by Rows tiles the spacing is
+// ignored, so the same grid works for packed and spaced versions of a sheet.
+func NewGridSpriteSheet(texture rl.Texture2D, grid SpriteGrid) (SpriteSheet, error) {
+	if grid.TileWidth <= 0 || grid.TileHeight <= 0 {
+		return SpriteSheet{}, fmt.Errorf("invalid tile size %dx%d", grid.TileWidth, grid.TileHeight)
+	}
+	width, height := int(texture.Width), int(texture.Height)
+	if grid.Columns > 0 && grid.Rows > 0 && width == grid.Columns*grid.TileWidth && height == grid.Rows*grid.TileHeight {
+		grid.SpacingX, grid.SpacingY = 0, 0
+	}
+	// n tiles take n*tile + (n-1)*spacing pixels
+	if grid.Columns <= 0 {
+		grid.Columns = (width + grid.SpacingX) / (grid.TileWidth + grid.SpacingX)
+	}
+	if grid.Rows <= 0 {
+		grid.Rows = (height + grid.SpacingY) / (grid.TileHeight + grid.SpacingY)
+	}
+
+	sheet := SpriteSheet{
+		texture: texture,
+		columns: grid.Columns,
+	}
+	size := NewVector2(float64(grid.TileWidth), float64(grid.TileHeight))
+	for row := 0; row < grid.Rows; row++ {
+		for column := 0; column < grid.Columns; column++ {
+			sheet.frames = append(sheet.frames, SpriteFrame{
+				Source: rl.NewRectangle(
+					float32(column*(grid.TileWidth+grid.SpacingX)),
+					float32(row*(grid.TileHeight+grid.SpacingY)),
+					float32(grid.TileWidth),
+					float32(grid.TileHeight),
+				),
+				Size: size,
+			})
+		}
+	}
+	return sheet, nil
+}
+
+var tilesheetNumber = regexp.MustCompile(`\d+`)
+
+// ParseTilesheet reads the Tilesheet.txt that comes with Kenney's packs,
+// lines like "Tile size • 16px × 16px"
+func ParseTilesheet(r io.Reader) (SpriteGrid, error) {
+	var grid SpriteGrid
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "•")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		var numbers []int
+		for _, s := range tilesheetNumber.FindAllString(value, -1) {
+			n, _ := strconv.Atoi(s)
+			numbers = append(numbers, n)
+		}
+		if len(numbers) == 0 {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(key, "tile size") && len(numbers) == 2:
+			grid.TileWidth, grid.TileHeight = numbers[0], numbers[1]
+		case strings.HasPrefix(key, "space between tiles") && len(numbers) == 2:
+			grid.SpacingX, grid.SpacingY = numbers[0], numbers[1]
+		case strings.HasPrefix(key, "total tiles (horizontal)"):
+			grid.Columns = numbers[0]
+		case strings.HasPrefix(key, "total tiles (vertical)"):
+			grid.Rows = numbers[0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return SpriteGrid{}, err
+	}
+	if grid.TileWidth == 0 || grid.TileHeight == 0 {
+		return SpriteGrid{}, errors.New("tilesheet has no tile size")
+	}
+	return grid, nil
+}
+
+func LoadTilesheet(path string) (SpriteGrid, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return SpriteGrid{}, err
+	}
+	defer file.Close()
+	return ParseTilesheet(file)
+}
+
+type atlasRect struct {
+	X float32 `json:"x"`
+	Y float32 `json:"y"`
+	W float32 `json:"w"`
+	H float32 `json:"h"`
+}
+
+type atlasFrame struct {
+	Filename         string    `json:"filename"`
+	Frame            atlasRect `json:"frame"`
+	Rotated          bool      `json:"rotated"`
+	Trimmed          bool      `json:"trimmed"`
+	SpriteSourceSize atlasRect `json:"spriteSourceSize"`
+	SourceSize       atlasRect `json:"sourceSize"`
+	Duration         float64   `json:"duration"` // milliseconds
+}
+
+func (f atlasFrame) toSpriteFrame(name string) (SpriteFrame, error) {
+	if f.Rotated {
+		return SpriteFrame{}, fmt.Errorf("atlas frame %q is rotated, which isn't supported", name)
+	}
+	frame := SpriteFrame{
+		Name:     name,
+		Source:   rl.NewRectangle(f.Frame.X, f.Frame.Y, f.Frame.W, f.Frame.H),
+		Size:     NewVector2(float64(f.Frame.W), float64(f.Frame.H)),
+		Duration: f.Duration / 1000,
+	}
+	if f.Trimmed {
+		frame.Offset = NewVector2(float64(f.SpriteSourceSize.X), float64(f.SpriteSourceSize.Y))
+	}
+	if f.SourceSize.W > 0 && f.SourceSize.H > 0 {
+		frame.Size = NewVector2(float64(f.SourceSize.W), float64(f.SourceSize.H))
+	}
+	return frame, nil
+}
+
+// parseAtlasFrames reads the frames of a JSON atlas, as written by
+// TexturePacker or Aseprite in either their hash or array layouts. Frames
+// keep the order they have in the file.
+func parseAtlasFrames(data []byte) ([]SpriteFrame, error) {
+	var atlas struct {
+		Frames json.RawMessage `json:"frames"`
+	}
+	if err := json.Unmarshal(data, &atlas); err != nil {
+		return nil, err
+	}
+	raw := bytes.TrimSpace(atlas.Frames)
+	if len(raw) == 0 {
+		return nil, errors.New("atlas has no frames")
+	}
+
+	var frames []SpriteFrame
+	if raw[0] == '[' {
+		var list []atlasFrame
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return nil, err
+		}
+		for _, f := range list {
+			frame, err := f.toSpriteFrame(f.Filename)
+			if err != nil {
+				return nil, err
+			}
+			frames = append(frames, frame)
+		}
+		return frames, nil
+	}
+
+	// walk the object by hand since a map would lose the order
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		name, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected %v in atlas frames", token)
+		}
+		var f atlasFrame
+		if err := decoder.Decode(&f); err != nil {
+			return nil, err
+		}
+		frame, err := f.toSpriteFrame(name)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+// NewAtlasSpriteSheet slices a texture using a JSON atlas, as written by
+// TexturePacker or Aseprite. Frames can be found by index or by name.
+func NewAtlasSpriteSheet(texture rl.Texture2D, data []byte) (SpriteSheet, error) {
+	frames, err := parseAtlasFrames(data)
+	if err != nil {
+		return SpriteSheet{}, err
+	}
+	sheet := SpriteSheet{
+		texture: texture,
+		frames:  frames,
+		names:   make(map[string]int, len(frames)),
+	}
+	for i, frame := range frames {
+		sheet.names[frame.Name] = i
+	}
+	return sheet, nil
+}
+
+func LoadAtlasSpriteSheet(texture rl.Texture2D, path string) (SpriteSheet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SpriteSheet{}, err
+	}
+	return NewAtlasSpriteSheet(texture, data)
+}
+
+func (s SpriteSheet) Texture() rl.Texture2D {
+	return s.texture
+}
+
+func (s SpriteSheet) Len() int {
+	return len(s.frames)
+}
+
+func (s SpriteSheet) Frame(i int) SpriteFrame {
+	return s.frames[i]
+}
+
+func (s SpriteSheet) FrameByName(name string) (SpriteFrame, bool) {
+	i, ok := s.names[name]
+	if !ok {
+		return SpriteFrame{}, false
+	}
+	return s.frames[i], true
+}
+
+// Tile is the frame at a column and row of a grid sheet
+func (s SpriteSheet) Tile(column int, row int) SpriteFrame {
+	if s.columns == 0 || column < 0 || column >= s.columns {
+		panic(fmt.Sprintf("no tile at column %d of sprite sheet", column))
+	}
+	return s.frames[row*s.columns+column]
+}
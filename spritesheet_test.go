@@ -0,0 +1,96 @@
+package raychip
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+func TestParseTilesheet(t *testing.T) {
+	grid, err := LoadTilesheet(filepath.Join("examples", "physics", "assets", "cursors", "Tilesheet.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := SpriteGrid{TileWidth: 16, TileHeight: 16, SpacingX: 1, SpacingY: 1, Columns: 20, Rows: 11}
+	if grid != want {
+		t.Errorf("got %+v, want %+v", grid, want)
+	}
+
+	if _, err := ParseTilesheet(strings.NewReader("Total tiles (horizontal) • 4 tiles\n")); err == nil {
+		t.Errorf("tilesheet without a tile size parsed")
+	}
+}
+
+func TestGridSpriteSheet(t *testing.T) {
+	grid := SpriteGrid{TileWidth: 16, TileHeight: 16, SpacingX: 1, SpacingY: 1, Columns: 20, Rows: 11}
+	tests := []struct {
+		name    string
+		texture rl.Texture2D
+		grid    SpriteGrid
+		len     int
+		tile    rl.Rectangle
+	}{
+		{"spaced", rl.Texture2D{Width: 339, Height: 186}, grid, 220, rl.NewRectangle(34, 17, 16, 16)},
+		{"packed", rl.Texture2D{Width: 320, Height: 176}, grid, 220, rl.NewRectangle(32, 16, 16, 16)},
+		{"fitted", rl.Texture2D{Width: 50, Height: 33}, SpriteGrid{TileWidth: 16, TileHeight: 16, SpacingX: 1, SpacingY: 1}, 6, rl.NewRectangle(34, 17, 16, 16)},
+	}
+	for _, test := range tests {
+		sheet, err := NewGridSpriteSheet(test.texture, test.grid)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if sheet.Len() != test.len {
+			t.Errorf("%s: %d tiles, want %d", test.name, sheet.Len(), test.len)
+		}
+		if got := sheet.Tile(2, 1).Source; got != test.tile {
+			t.Errorf("%s: tile 2,1 at %v, want %v", test.name, got, test.tile)
+		}
+	}
+
+	if _, err := NewGridSpriteSheet(rl.Texture2D{Width: 16, Height: 16}, SpriteGrid{}); err == nil {
+		t.Errorf("grid without a tile size was accepted")
+	}
+}
+
+func TestAtlasSpriteSheet(t *testing.T) {
+	hash := `{"frames": {
+		"walk 1": {"frame": {"x": 0, "y": 0, "w": 8, "h": 10}, "trimmed": true,
+			"spriteSourceSize": {"x": 2, "y": 3, "w": 8, "h": 10}, "sourceSize": {"w": 16, "h": 16}},
+		"walk 0": {"frame": {"x": 8, "y": 0, "w": 16, "h": 16}}
+	}}`
+	array := `{"frames": [
+		{"filename": "walk 1", "frame": {"x": 0, "y": 0, "w": 8, "h": 10}, "trimmed": true,
+			"spriteSourceSize": {"x": 2, "y": 3, "w": 8, "h": 10}, "sourceSize": {"w": 16, "h": 16}},
+		{"filename": "walk 0", "frame": {"x": 8, "y": 0, "w": 16, "h": 16}}
+	]}`
+	for name, data := range map[string]string{"hash": hash, "array": array} {
+		sheet, err := NewAtlasSpriteSheet(rl.Texture2D{}, []byte(data))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		// frames keep the file's order rather than being sorted
+		if sheet.Len() != 2 || sheet.Frame(0).Name != "walk 1" || sheet.Frame(1).Name != "walk 0" {
+			t.Errorf("%s: frames %+v out of order", name, sheet.frames)
+		}
+		trimmed, ok := sheet.FrameByName("walk 1")
+		want := SpriteFrame{
+			Name:   "walk 1",
+			Source: rl.NewRectangle(0, 0, 8, 10),
+			Offset: NewVector2(2, 3),
+			Size:   NewVector2(16, 16),
+		}
+		if !ok || trimmed != want {
+			t.Errorf("%s: trimmed frame %+v, want %+v", name, trimmed, want)
+		}
+		if _, ok := sheet.FrameByName("run 0"); ok {
+			t.Errorf("%s: found a frame that isn't there", name)
+		}
+	}
+
+	rotated := `{"frames": [{"filename": "a", "frame": {"x": 0, "y": 0, "w": 8, "h": 8}, "rotated": true}]}`
+	if _, err := NewAtlasSpriteSheet(rl.Texture2D{}, []byte(rotated)); err == nil {
+		t.Errorf("rotated frame was accepted")
+	}
+}
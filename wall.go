@@ -33,17 +33,17 @@ func (e *Wall) addToGame(game *Game, args ...any) {
 			shape = game.space.AddShape(cp.NewSegment(body, cp.Vector{X: e.vertex1.X, Y: e.vertex1.Y}, cp.Vector{X: e.vertex2.X, Y: e.vertex2.Y}, e.width/2))
 			shape.SetElasticity(1)
 			shape.SetFriction(1)
-			e.id = uint64(len(game.entities))
+			e.cpBody = body
+			e.cpShape = shape
 		}
 	}
-	e.id = uint64(len(game.entities))
-	game.entities = append(game.entities, e)
+	game.registerEntity(e)
 }
 
 func (w *Wall) Update() {}
 
 func (w *Wall) Draw() {
-	rl.DrawLineEx(w.vertex1.ToRaylib(), w.vertex2.ToRaylib(), float32(w.width), w.color)
+	w.renderer().DrawLine(w.vertex1, w.vertex2, w.width, w.color)
 }
 
 func (w Wall) Vertex1() Vector2 {